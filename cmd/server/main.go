@@ -5,6 +5,10 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -16,6 +20,7 @@ import (
 	"web-crawler-go/internal/adapters/secondary/providers/shopify"
 	"web-crawler-go/internal/adapters/secondary/providers/shopline"
 	"web-crawler-go/internal/adapters/secondary/repository"
+	"web-crawler-go/internal/adapters/secondary/warc"
 
 	// Core
 	"web-crawler-go/internal/core/ports"
@@ -24,6 +29,14 @@ import (
 )
 
 func main() {
+	// Set when the server fails, and applied after the deferred closes have run
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
 	// Load environment variables from .env file
 	if err := godotenv.Load(".env.development"); err != nil {
 		log.Println("No .env file found or error loading .env file:", err)
@@ -35,33 +48,164 @@ func main() {
 
 	// 1. Initialize Secondary/Driven Adapters
 
-	// Initialize Redis cache
-	redisHost := getEnvWithDefault("REDIS_HOST", "localhost:6379")
-	redisPassword := getEnvWithDefault("REDIS_PASSWORD", "")
-	redisCache := cache.NewRedisCache(redisHost, redisPassword, 0)
+	// Initialize the fetch cache: Redis when an address is configured, in-memory otherwise
+	redisConfigured := os.Getenv("REDIS_URL") != "" || os.Getenv("REDIS_HOST") != "" || os.Getenv("REDIS_ADDRS") != ""
+	defaultCacheBackend := "memory"
+	if redisConfigured {
+		defaultCacheBackend = "redis"
+	}
 
-	// Initialize HTTP fetcher with Redis cache
-	htmlFetcher := fetcher.NewHTTPFetcher(redisCache, logger)
+	cacheBackend := getEnvWithDefault("CACHE_BACKEND", defaultCacheBackend)
+
+	var cacheService ports.CacheService
+	switch cacheBackend {
+	case "redis":
+		redisCache := newRedisCache()
+		defer redisCache.Close()
+		cacheService = redisCache
+	case "memory":
+		memoryCache := newMemoryCache()
+		defer memoryCache.Close()
+		cacheService = memoryCache
+	case "disk":
+		diskCache := newDiskCache()
+		defer diskCache.Close()
+		cacheService = diskCache
+	case "tiered":
+		// L1 memory, then Redis and disk when they are configured
+		memoryCache := newMemoryCache()
+		defer memoryCache.Close()
+		tiers := []cache.CacheTier{{
+			Name:         "memory",
+			Cache:        memoryCache,
+			WritePolicy:  cache.WritePolicy(getEnvWithDefault("CACHE_MEMORY_WRITE_POLICY", string(cache.WriteThrough))),
+			PromotionTTL: getDurationEnv("CACHE_MEMORY_PROMOTION_TTL", time.Hour),
+		}}
+		if redisConfigured {
+			redisCache := newRedisCache()
+			defer redisCache.Close()
+			tiers = append(tiers, cache.CacheTier{
+				Name:         "redis",
+				Cache:        redisCache,
+				WritePolicy:  cache.WritePolicy(getEnvWithDefault("CACHE_REDIS_WRITE_POLICY", string(cache.WriteThrough))),
+				PromotionTTL: getDurationEnv("CACHE_REDIS_PROMOTION_TTL", 24*time.Hour),
+			})
+		}
+		if os.Getenv("CACHE_DISK_DIR") != "" {
+			diskCache := newDiskCache()
+			defer diskCache.Close()
+			tiers = append(tiers, cache.CacheTier{
+				Name:        "disk",
+				Cache:       diskCache,
+				WritePolicy: cache.WritePolicy(getEnvWithDefault("CACHE_DISK_WRITE_POLICY", string(cache.WriteAsync))),
+			})
+		}
+		tieredCache, err := cache.NewTieredCache(logger, tiers...)
+		if err != nil {
+			log.Fatalf("Failed to initialize tiered cache: %v", err)
+		}
+		cacheService = tieredCache
+	default:
+		log.Fatalf("Unknown CACHE_BACKEND %q", cacheBackend)
+	}
+	logger.Info("cache initialized", "backend", cacheBackend)
+
+	// Initialize the fetcher: live HTTP with the cache, or replay of a recording
+	var (
+		htmlFetcher  ports.HTMLFetcher
+		proxyMonitor ports.ProxyMonitor
+		cacheAdmin   ports.CacheAdmin
+	)
+	fetcherMode := getEnvWithDefault("FETCHER_MODE", "live")
+	switch fetcherMode {
+	case "live":
+		fetcherConfig, err := fetcher.LoadConfig(os.Getenv("FETCHER_CONFIG_FILE"))
+		if err != nil {
+			log.Fatalf("Failed to load fetcher configuration: %v", err)
+		}
+		// Optionally archive every download as WARC
+		var recorder ports.FetchRecorder
+		if warcDir := os.Getenv("WARC_DIR"); warcDir != "" {
+			maxFileSize, err := strconv.ParseInt(getEnvWithDefault("WARC_MAX_FILE_SIZE", "1073741824"), 10, 64)
+			if err != nil {
+				log.Fatalf("Invalid WARC_MAX_FILE_SIZE: %v", err)
+			}
+			warcWriter, err := warc.NewWriter(warc.WriterConfig{
+				Dir:         warcDir,
+				Prefix:      getEnvWithDefault("WARC_PREFIX", "crawl"),
+				MaxFileSize: maxFileSize,
+			}, logger)
+			if err != nil {
+				log.Fatalf("Failed to initialize WARC recording: %v", err)
+			}
+			// Closed on shutdown, which finishes the current file and sorts its CDX index
+			defer func() {
+				if err := warcWriter.Close(); err != nil {
+					logger.Error("failed to close WARC writer", "error", err)
+				}
+			}()
+			recorder = warcWriter
+			logger.Info("recording fetches as WARC", "dir", warcDir)
+		}
+
+		httpFetcher, err := fetcher.NewHTTPFetcher(cacheService, recorder, fetcherConfig, logger)
+		if err != nil {
+			log.Fatalf("Failed to initialize HTTP fetcher: %v", err)
+		}
+		htmlFetcher, proxyMonitor, cacheAdmin = httpFetcher, httpFetcher, httpFetcher
+	case "replay":
+		replayFetcher, err := fetcher.NewReplayFetcher(os.Getenv("REPLAY_SOURCE"), os.Getenv("REPLAY_RUN_ID"), logger)
+		if err != nil {
+			log.Fatalf("Failed to load replay archive: %v", err)
+		}
+		htmlFetcher, proxyMonitor, cacheAdmin = replayFetcher, replayFetcher, replayFetcher
+	default:
+		log.Fatalf("Unknown FETCHER_MODE %q", fetcherMode)
+	}
+	logger.Info("fetcher initialized", "mode", fetcherMode)
 
-	// Initialize MongoDB repository
+	// Initialize the product repository
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	mongoDBURI := getEnvWithDefault("MONGODB_URI", "mongodb://localhost:27017")
+	var productRepo ports.ProductRepository
+	repositoryBackend := getEnvWithDefault("REPOSITORY_BACKEND", "mongodb")
+	switch repositoryBackend {
+	case "mongodb":
+		mongoDBURI := getEnvWithDefault("MONGODB_URI", "mongodb://localhost:27017")
 
-	mongoDBRepo, err := repository.NewMongoDBRepository(
-		ctx,
-		mongoDBURI,
-		"web-crawler", // Database name
-		"products",    // Collection name
-		logger,
-	)
-	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB: %v", err)
+		mongoDBRepo, err := repository.NewMongoDBRepository(
+			ctx,
+			mongoDBURI,
+			"web-crawler", // Database name
+			"products",    // Collection name
+			logger,
+		)
+		if err != nil {
+			log.Fatalf("Failed to connect to MongoDB: %v", err)
+		}
+		productRepo = mongoDBRepo
+	case "postgres":
+		postgresRepo, err := repository.NewPostgresRepository(ctx, getEnvWithDefault("POSTGRES_DSN", "postgres://localhost:5432/web_crawler"), logger)
+		if err != nil {
+			log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+		}
+		productRepo = postgresRepo
+	case "sqlite":
+		sqliteRepo, err := repository.NewSQLiteRepository(ctx, getEnvWithDefault("SQLITE_PATH", "web-crawler.db"), logger)
+		if err != nil {
+			log.Fatalf("Failed to open SQLite database: %v", err)
+		}
+		productRepo = sqliteRepo
+	case "memory":
+		// Products are lost when the server stops
+		productRepo = repository.NewMemoryRepository(logger)
+	default:
+		log.Fatalf("Unknown REPOSITORY_BACKEND %q", repositoryBackend)
 	}
 	defer func() {
-		if err := mongoDBRepo.Close(context.Background()); err != nil {
-			logger.Error("Failed to close MongoDB connection", "error", err)
+		if err := productRepo.Close(context.Background()); err != nil {
+			logger.Error("Failed to close repository", "backend", repositoryBackend, "error", err)
 		}
 	}()
 
@@ -78,19 +222,164 @@ func main() {
 
 	// 3. Initialize the Core Services (injecting dependencies)
 	sseService := services.NewSSEService(logger)
-	productService := services.NewProductService(htmlFetcher, providerRegistry, mongoDBRepo, sseService, logger)
+	productService := services.NewProductService(htmlFetcher, providerRegistry, productRepo, sseService, getDurationEnv("CRAWL_INTERVAL", 24*time.Hour), logger)
 
 	// 4. Initialize Primary/Driving Adapters (injecting services)
-	router := httpadapter.NewRouter(productService, sseService, logger)
+	router := httpadapter.NewRouter(productService, sseService, proxyMonitor, cacheAdmin, logger)
 
 	// 5. Setup Router and Start Server
 	handler := router.SetupRoutes()
 
 	port := getEnvWithDefault("PORT", "8080")
+	server := &http.Server{Addr: ":" + port, Handler: handler}
 	log.Printf("Server starting on :%s...", port)
-	if err := http.ListenAndServe(":"+port, handler); err != nil {
-		log.Fatalf("could not start server: %v", err)
+	if err := serve(server, getDurationEnv("SHUTDOWN_TIMEOUT", 30*time.Second), logger); err != nil {
+		logger.Error("server stopped", "error", err)
+		exitCode = 1
+	}
+}
+
+// serve runs the server until it fails or SIGINT or SIGTERM asks it to stop. On a
+// signal, requests in progress get up to timeout to finish before their connections
+// are closed, and serve returns so main's deferred closes run.
+func serve(server *http.Server, timeout time.Duration, logger ports.Logger) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		return err
+	case <-ctx.Done():
+	}
+	// A second signal stops the process immediately
+	stop()
+
+	logger.Info("shutting down server", "timeout", timeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("requests still running after the shutdown timeout, closing their connections", "error", err)
+		return server.Close()
+	}
+	logger.Info("server stopped")
+	return nil
+}
+
+// newRedisCache builds the Redis cache from REDIS_* variables
+func newRedisCache() *cache.RedisCache {
+	codec, err := cache.ParseCodec(getEnvWithDefault("CACHE_REDIS_CODEC", string(cache.CodecZstd)))
+	if err != nil {
+		log.Fatalf("Invalid CACHE_REDIS_CODEC: %v", err)
+	}
+
+	// REDIS_ADDRS lists sentinels or cluster seed nodes; REDIS_HOST is the single standalone address
+	var addrs []string
+	if value := getEnvWithDefault("REDIS_ADDRS", os.Getenv("REDIS_HOST")); value != "" {
+		for _, addr := range strings.Split(value, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+
+	redisCache, err := cache.NewRedisCache(cache.RedisConfig{
+		URL:                   os.Getenv("REDIS_URL"),
+		Mode:                  cache.RedisMode(getEnvWithDefault("REDIS_MODE", string(cache.RedisStandalone))),
+		Addrs:                 addrs,
+		MasterName:            os.Getenv("REDIS_MASTER_NAME"),
+		Username:              os.Getenv("REDIS_USERNAME"),
+		Password:              os.Getenv("REDIS_PASSWORD"),
+		SentinelUsername:      os.Getenv("REDIS_SENTINEL_USERNAME"),
+		SentinelPassword:      os.Getenv("REDIS_SENTINEL_PASSWORD"),
+		DB:                    getIntEnv("REDIS_DB", 0),
+		TLS:                   getBoolEnv("REDIS_TLS", false),
+		TLSCAFile:             os.Getenv("REDIS_TLS_CA_FILE"),
+		TLSServerName:         os.Getenv("REDIS_TLS_SERVER_NAME"),
+		TLSInsecureSkipVerify: getBoolEnv("REDIS_TLS_INSECURE_SKIP_VERIFY", false),
+		PoolSize:              getIntEnv("REDIS_POOL_SIZE", 0),
+		MinIdleConns:          getIntEnv("REDIS_MIN_IDLE_CONNS", 0),
+		MaxIdleConns:          getIntEnv("REDIS_MAX_IDLE_CONNS", 0),
+		ConnMaxIdleTime:       getDurationEnv("REDIS_CONN_MAX_IDLE_TIME", 0),
+		PoolTimeout:           getDurationEnv("REDIS_POOL_TIMEOUT", 0),
+		DialTimeout:           getDurationEnv("REDIS_DIAL_TIMEOUT", 0),
+		ReadTimeout:           getDurationEnv("REDIS_READ_TIMEOUT", 0),
+		WriteTimeout:          getDurationEnv("REDIS_WRITE_TIMEOUT", 0),
+		Codec:                 codec,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize Redis cache: %v", err)
+	}
+	return redisCache
+}
+
+// newMemoryCache builds the in-memory cache from CACHE_MEMORY_* variables
+func newMemoryCache() *cache.MemoryCache {
+	maxBytes, err := strconv.ParseInt(getEnvWithDefault("CACHE_MEMORY_MAX_BYTES", "268435456"), 10, 64)
+	if err != nil {
+		log.Fatalf("Invalid CACHE_MEMORY_MAX_BYTES: %v", err)
+	}
+	return cache.NewMemoryCache(maxBytes, getDurationEnv("CACHE_MEMORY_SWEEP_INTERVAL", time.Minute))
+}
+
+// newDiskCache builds the disk cache from CACHE_DISK_* variables
+func newDiskCache() *cache.FileSystemCache {
+	maxBytes, err := strconv.ParseInt(getEnvWithDefault("CACHE_DISK_MAX_BYTES", "10737418240"), 10, 64)
+	if err != nil {
+		log.Fatalf("Invalid CACHE_DISK_MAX_BYTES: %v", err)
+	}
+	diskCache, err := cache.NewFileSystemCache(
+		getEnvWithDefault("CACHE_DISK_DIR", "cache"),
+		maxBytes,
+		getDurationEnv("CACHE_DISK_SWEEP_INTERVAL", 10*time.Minute),
+	)
+	if err != nil {
+		log.Fatalf("Failed to initialize disk cache: %v", err)
+	}
+	return diskCache
+}
+
+// getDurationEnv parses a duration environment variable, exiting on invalid values
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return duration
+}
+
+// getIntEnv parses an integer environment variable, exiting on invalid values
+func getIntEnv(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return n
+}
+
+// getBoolEnv parses a boolean environment variable, exiting on invalid values
+func getBoolEnv(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
 	}
+	return b
 }
 
 // Helper function to get environment variable with default value
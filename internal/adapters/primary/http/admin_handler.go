@@ -0,0 +1,151 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"unicode/utf8"
+	"web-crawler-go/internal/core/ports"
+)
+
+// AdminHandler exposes operational endpoints for the crawler internals
+type AdminHandler struct {
+	proxyMonitor ports.ProxyMonitor
+	cacheAdmin   ports.CacheAdmin
+	logger       ports.Logger
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(proxyMonitor ports.ProxyMonitor, cacheAdmin ports.CacheAdmin, logger ports.Logger) *AdminHandler {
+	return &AdminHandler{
+		proxyMonitor: proxyMonitor,
+		cacheAdmin:   cacheAdmin,
+		logger:       logger,
+	}
+}
+
+// GetProxyHealth returns the health of every proxy in the fetcher's pool
+func (h *AdminHandler) GetProxyHealth(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("proxy health request", "remoteAddr", r.RemoteAddr)
+
+	statuses := h.proxyMonitor.ProxyStatuses()
+
+	healthy := 0
+	for _, status := range statuses {
+		if status.Healthy {
+			healthy++
+		}
+	}
+
+	RespondSuccess(w, h.logger, http.StatusOK, "Proxy pool status", map[string]interface{}{
+		"total":   len(statuses),
+		"healthy": healthy,
+		"proxies": statuses,
+	}, nil)
+}
+
+// GetCacheStats returns the statistics of the fetch cache
+func (h *AdminHandler) GetCacheStats(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("cache stats request", "remoteAddr", r.RemoteAddr)
+
+	stats, err := h.cacheAdmin.CacheStats(r.Context())
+	if err != nil {
+		h.respondCacheError(w, "failed to get cache stats", err)
+		return
+	}
+
+	RespondSuccess(w, h.logger, http.StatusOK, "Cache statistics", stats, nil)
+}
+
+// GetCacheEntry looks up the cache entry for the url query parameter
+func (h *AdminHandler) GetCacheEntry(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("cache lookup request", "remoteAddr", r.RemoteAddr)
+
+	targetURL, ok := h.urlParam(w, r)
+	if !ok {
+		return
+	}
+
+	entry, found, err := h.cacheAdmin.LookupURL(r.Context(), targetURL)
+	if err != nil {
+		h.respondCacheError(w, "failed to look up cache entry", err)
+		return
+	}
+	if !found {
+		RespondError(w, h.logger, http.StatusNotFound, "URL is not cached", nil)
+		return
+	}
+
+	RespondSuccess(w, h.logger, http.StatusOK, "Cache entry found", entry, nil)
+}
+
+// DeleteCacheEntries removes the entry for the url query parameter, or every entry
+// for the domain query parameter and its subdomains
+func (h *AdminHandler) DeleteCacheEntries(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("cache invalidation request", "remoteAddr", r.RemoteAddr)
+
+	query := r.URL.Query()
+	switch {
+	case query.Get("domain") != "":
+		domainName := query.Get("domain")
+		if utf8.RuneCountInString(domainName) > 253 || !validDomainPattern.MatchString(domainName) {
+			h.logger.Error("invalid domain name format", "domainName", domainName)
+			RespondError(w, h.logger, http.StatusBadRequest, "Invalid domain name format", nil)
+			return
+		}
+
+		deleted, err := h.cacheAdmin.InvalidateDomain(r.Context(), domainName)
+		if err != nil {
+			h.respondCacheError(w, "failed to purge cache domain", err)
+			return
+		}
+		RespondSuccess(w, h.logger, http.StatusOK, "Cache purged for domain", map[string]interface{}{
+			"domain":  domainName,
+			"deleted": deleted,
+		}, nil)
+
+	case query.Get("url") != "":
+		targetURL, ok := h.urlParam(w, r)
+		if !ok {
+			return
+		}
+
+		if err := h.cacheAdmin.InvalidateURL(r.Context(), targetURL); err != nil {
+			h.respondCacheError(w, "failed to invalidate cache entry", err)
+			return
+		}
+		RespondSuccess(w, h.logger, http.StatusOK, "Cache entry removed", map[string]string{"url": targetURL}, nil)
+
+	default:
+		h.logger.Error("missing url or domain parameter")
+		RespondError(w, h.logger, http.StatusBadRequest, "url or domain parameter is required", nil)
+	}
+}
+
+// urlParam reads and validates the url query parameter, responding on failure
+func (h *AdminHandler) urlParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	targetURL := r.URL.Query().Get("url")
+	if targetURL == "" {
+		h.logger.Error("missing url parameter")
+		RespondError(w, h.logger, http.StatusBadRequest, "url parameter is required", nil)
+		return "", false
+	}
+
+	parsed, err := url.Parse(targetURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		h.logger.Error("invalid url parameter", "url", targetURL)
+		RespondError(w, h.logger, http.StatusBadRequest, "url must be an absolute http or https URL", nil)
+		return "", false
+	}
+	return targetURL, true
+}
+
+// respondCacheError maps cache admin errors to a response
+func (h *AdminHandler) respondCacheError(w http.ResponseWriter, message string, err error) {
+	h.logger.Error(message, "error", err)
+	if errors.Is(err, ports.ErrCacheUnsupported) {
+		RespondError(w, h.logger, http.StatusNotImplemented, "Not supported by the configured cache", err.Error())
+		return
+	}
+	RespondError(w, h.logger, http.StatusInternalServerError, "Internal server error", err.Error())
+}
@@ -1,8 +1,10 @@
 package http
 
 import (
+	"errors"
 	http2 "net/http"
 	"regexp"
+	"strconv"
 	"unicode/utf8"
 	"web-crawler-go/internal/core/ports"
 )
@@ -12,13 +14,15 @@ var validDomainPattern = regexp.MustCompile(`^([a-zA-Z0-9]{1}[a-zA-Z0-9-]{0,61}[
 // CrawlerHandler handles Server-Sent Events HTTP connections
 type CrawlerHandler struct {
 	productService ports.ProductService
+	cacheAdmin     ports.CacheAdmin
 	logger         ports.Logger
 }
 
 // NewCrawlerHandler creates a new Crawler handler
-func NewCrawlerHandler(productService ports.ProductService, logger ports.Logger) *CrawlerHandler {
+func NewCrawlerHandler(productService ports.ProductService, cacheAdmin ports.CacheAdmin, logger ports.Logger) *CrawlerHandler {
 	return &CrawlerHandler{
 		productService: productService,
+		cacheAdmin:     cacheAdmin,
 		logger:         logger,
 	}
 }
@@ -48,9 +52,41 @@ func (h *CrawlerHandler) CrawlDomain(w http2.ResponseWriter, r *http2.Request) {
 		return
 	}
 
+	// Cache mode: use (default), refresh or bypass
+	cacheMode, err := ports.ParseCacheMode(r.URL.Query().Get("cache"))
+	if err != nil {
+		h.logger.Error("invalid cache parameter", "error", err)
+		RespondError(w, h.logger, http2.StatusBadRequest, "Invalid cache parameter", err.Error())
+		return
+	}
+
+	// Optionally purge the domain's cached pages so the crawl downloads fresh data
+	purge := false
+	if value := r.URL.Query().Get("purge"); value != "" {
+		purge, err = strconv.ParseBool(value)
+		if err != nil {
+			h.logger.Error("invalid purge parameter", "purge", value)
+			RespondError(w, h.logger, http2.StatusBadRequest, "Invalid purge parameter", "purge must be true or false")
+			return
+		}
+	}
+	if purge {
+		deleted, err := h.cacheAdmin.InvalidateDomain(r.Context(), domainName)
+		if err != nil {
+			h.logger.Error("failed to purge cache before crawl", "domainName", domainName, "error", err)
+			if errors.Is(err, ports.ErrCacheUnsupported) {
+				RespondError(w, h.logger, http2.StatusNotImplemented, "Not supported by the configured cache", err.Error())
+				return
+			}
+			RespondError(w, h.logger, http2.StatusInternalServerError, "Internal server error", err.Error())
+			return
+		}
+		h.logger.Info("purged cache before crawl", "domainName", domainName, "deleted", deleted)
+	}
+
 	// 2. Get products from the service
 	domainUrl := "https://" + domainName
-	productsCount, err := h.productService.CrawlAndSaveProductsFromURL(r.Context(), domainUrl)
+	productsCount, err := h.productService.CrawlAndSaveProductsFromURL(r.Context(), domainUrl, ports.CrawlOptions{CacheMode: cacheMode})
 	if err != nil {
 		h.logger.Error("failed to get productsCount", "error", err)
 		RespondError(w, h.logger, http2.StatusInternalServerError, "Internal server error", err.Error())
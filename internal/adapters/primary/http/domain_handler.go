@@ -0,0 +1,111 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"web-crawler-go/internal/core/ports"
+)
+
+type DomainHandler struct {
+	service ports.ProductService
+	logger  ports.Logger
+}
+
+func NewDomainHandler(service ports.ProductService, logger ports.Logger) *DomainHandler {
+	return &DomainHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListDomains returns the catalogue of stored domains with their product counts
+// and latest crawl
+func (h *DomainHandler) ListDomains(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("received request", "method", r.Method, "url", r.URL.String())
+
+	query, err := parseDomainQuery(r.URL.Query())
+	if err != nil {
+		h.logger.Error("invalid domain query", "error", err)
+		RespondError(w, h.logger, http.StatusBadRequest, "Invalid query parameter", err.Error())
+		return
+	}
+
+	result, err := h.service.ListDomains(r.Context(), query)
+	if err != nil {
+		h.logger.Error("failed to list domains", "error", err)
+		RespondError(w, h.logger, http.StatusInternalServerError, "Internal server error", err.Error())
+		return
+	}
+
+	pagination := &Pagination{
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+		TotalItems: result.TotalItems,
+		TotalPages: int(math.Ceil(float64(result.TotalItems) / float64(query.PageSize))),
+	}
+	if query.Page < pagination.TotalPages {
+		pagination.NextPage = pageLink(r, query.Page+1)
+	}
+	if query.Page > 1 {
+		pagination.PrevPage = pageLink(r, query.Page-1)
+	}
+
+	h.logger.Info("successfully listed domains", "count", len(result.Domains), "page", query.Page, "pageSize", query.PageSize)
+
+	RespondSuccess(w, h.logger, http.StatusOK, "Domains retrieved successfully", result.Domains, pagination)
+}
+
+// GetDomainStats returns price, stock, discount, tag and image statistics of a
+// domain's stored products
+func (h *DomainHandler) GetDomainStats(w http.ResponseWriter, r *http.Request) {
+	domainName := r.PathValue("domain")
+	h.logger.Info("received request", "method", r.Method, "domain", domainName)
+
+	stats, err := h.service.GetDomainStats(r.Context(), domainName)
+	if errors.Is(err, ports.ErrDomainNotFound) {
+		RespondError(w, h.logger, http.StatusNotFound, "Domain not found", nil)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get domain stats", "domain", domainName, "error", err)
+		RespondError(w, h.logger, http.StatusInternalServerError, "Internal server error", err.Error())
+		return
+	}
+
+	RespondSuccess(w, h.logger, http.StatusOK, "Domain stats retrieved successfully", stats, nil)
+}
+
+// parseDomainQuery reads the sort and page parameters of the domain listing
+func parseDomainQuery(values url.Values) (ports.DomainQuery, error) {
+	var query ports.DomainQuery
+
+	sort, err := ports.ParseDomainSortKey(values.Get("sort"))
+	if err != nil {
+		return query, err
+	}
+	query.Sort = sort
+
+	switch order := values.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return query, fmt.Errorf("order must be asc or desc, got %q", order)
+	}
+
+	query.Page, _ = strconv.Atoi(values.Get("page"))
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+
+	query.PageSize, _ = strconv.Atoi(values.Get("page_size"))
+	if query.PageSize <= 0 {
+		query.PageSize = 10 // Default page size
+	}
+
+	return query, nil
+}
@@ -8,12 +8,15 @@ type Response struct {
 	Pagination *Pagination `json:"pagination,omitempty"`
 }
 
-// Pagination represents the pagination metadata
+// Pagination represents the pagination metadata. Page is omitted for pages
+// requested by cursor.
 type Pagination struct {
-	Page       int    `json:"page"`
+	Page       int    `json:"page,omitempty"`
 	PageSize   int    `json:"page_size"`
 	TotalItems int    `json:"total_items"`
 	TotalPages int    `json:"total_pages"`
 	NextPage   string `json:"next_page,omitempty"`
 	PrevPage   string `json:"prev_page,omitempty"`
+	// NextCursor continues the listing after this page
+	NextCursor string `json:"next_cursor,omitempty"`
 }
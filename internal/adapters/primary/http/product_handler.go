@@ -1,10 +1,13 @@
 package http
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 	"web-crawler-go/internal/core/ports"
 )
 
@@ -31,46 +34,166 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 2. Pagination
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page <= 0 {
-		page = 1
-	}
-
-	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
-	if pageSize <= 0 {
-		pageSize = 10 // Default page size
+	// 2. Filters, sorting and pagination
+	query, err := parseProductQuery(r.URL.Query())
+	if err != nil {
+		h.logger.Error("invalid product query", "error", err)
+		RespondError(w, h.logger, http.StatusBadRequest, "Invalid query parameter", err.Error())
+		return
 	}
+	query.Domain = domainName
 
 	// 3. Get products from the service
-	products, totalItems, err := h.service.GetProductsByDomainName(r.Context(), domainName, page, pageSize)
+	result, err := h.service.ListProducts(r.Context(), query)
+	if errors.Is(err, ports.ErrInvalidCursor) {
+		h.logger.Error("invalid cursor", "error", err)
+		RespondError(w, h.logger, http.StatusBadRequest, "Invalid cursor parameter", err.Error())
+		return
+	}
 	if err != nil {
 		h.logger.Error("failed to get products", "error", err)
 		RespondError(w, h.logger, http.StatusInternalServerError, "Internal server error", err.Error())
 		return
 	}
 
-	totalPages := int(math.Ceil(float64(totalItems) / float64(pageSize)))
+	pageSize := query.PageSize
+	pagination := &Pagination{
+		PageSize:   pageSize,
+		TotalItems: result.TotalItems,
+		TotalPages: int(math.Ceil(float64(result.TotalItems) / float64(pageSize))),
+		NextCursor: result.NextCursor,
+	}
 
-	// 4. Construct the response
-	var nextPage, prevPage string
-	if page < totalPages {
-		nextPage = fmt.Sprintf("%s?domain_name=%s&page=%d&page_size=%d", r.URL.Path, domainName, page+1, pageSize)
+	// 4. Construct the response; links keep the filters and sort order. Cursor
+	// pages only link forward.
+	if query.Cursor != "" {
+		if result.NextCursor != "" {
+			pagination.NextPage = cursorLink(r, result.NextCursor)
+		}
+	} else {
+		page := query.Page
+		pagination.Page = page
+		if page < pagination.TotalPages {
+			pagination.NextPage = pageLink(r, page+1)
+		}
+		if page > 1 {
+			pagination.PrevPage = pageLink(r, page-1)
+		}
 	}
-	if page > 1 {
-		prevPage = fmt.Sprintf("%s?domain_name=%s&page=%d&page_size=%d", r.URL.Path, domainName, page-1, pageSize)
+
+	h.logger.Info("successfully retrieved products", "count", len(result.Products), "page", query.Page, "pageSize", pageSize, "cursor", query.Cursor != "")
+
+	RespondSuccess(w, h.logger, http.StatusOK, "Products retrieved successfully", result.Products, pagination)
+}
+
+// GetProductByID returns one product with its ID, source and timestamps
+func (h *ProductHandler) GetProductByID(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	h.logger.Info("received request", "method", r.Method, "id", id)
+
+	product, err := h.service.GetProduct(r.Context(), id)
+	if errors.Is(err, ports.ErrProductNotFound) {
+		RespondError(w, h.logger, http.StatusNotFound, "Product not found", nil)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get product", "id", id, "error", err)
+		RespondError(w, h.logger, http.StatusInternalServerError, "Internal server error", err.Error())
+		return
 	}
 
-	pagination := &Pagination{
-		Page:       page,
-		PageSize:   pageSize,
-		TotalItems: totalItems,
-		TotalPages: totalPages,
-		NextPage:   nextPage,
-		PrevPage:   prevPage,
+	RespondSuccess(w, h.logger, http.StatusOK, "Product retrieved successfully", product, nil)
+}
+
+// parseProductQuery reads the filter, sort and page parameters of a product listing
+func parseProductQuery(values url.Values) (ports.ProductQuery, error) {
+	query := ports.ProductQuery{
+		Status:     values.Get("status"),
+		Tag:        values.Get("tag"),
+		Search:     values.Get("q"),
+		CrawlRunID: values.Get("crawl_run_id"),
+		Provider:   values.Get("provider"),
+	}
+
+	for _, bound := range []struct {
+		name   string
+		target **int
+	}{{"min_price", &query.MinPrice}, {"max_price", &query.MaxPrice}} {
+		if value := values.Get(bound.name); value != "" {
+			price, err := strconv.Atoi(value)
+			if err != nil {
+				return query, fmt.Errorf("%s must be an integer", bound.name)
+			}
+			*bound.target = &price
+		}
+	}
+
+	if value := values.Get("on_sale"); value != "" {
+		onSale, err := strconv.ParseBool(value)
+		if err != nil {
+			return query, errors.New("on_sale must be true or false")
+		}
+		query.OnSale = onSale
+	}
+
+	for _, bound := range []struct {
+		name   string
+		target *time.Time
+	}{
+		{"updated_since", &query.UpdatedSince},
+		{"created_since", &query.CreatedSince},
+		{"crawled_since", &query.CrawledSince},
+		{"crawled_before", &query.CrawledBefore},
+	} {
+		if value := values.Get(bound.name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return query, fmt.Errorf("%s must be an RFC 3339 time, e.g. 2024-01-02T15:04:05Z", bound.name)
+			}
+			*bound.target = t
+		}
+	}
+
+	sort, err := ports.ParseProductSortKey(values.Get("sort"))
+	if err != nil {
+		return query, err
 	}
+	query.Sort = sort
 
-	h.logger.Info("successfully retrieved products", "count", len(products), "page", page, "pageSize", pageSize)
+	switch order := values.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return query, fmt.Errorf("order must be asc or desc, got %q", order)
+	}
+
+	query.Page, _ = strconv.Atoi(values.Get("page"))
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+
+	query.Cursor = values.Get("cursor")
+
+	query.PageSize, _ = strconv.Atoi(values.Get("page_size"))
+	if query.PageSize <= 0 {
+		query.PageSize = 10 // Default page size
+	}
+
+	return query, nil
+}
+
+// pageLink returns the request's path and query with the page number replaced
+func pageLink(r *http.Request, page int) string {
+	values := r.URL.Query()
+	values.Set("page", strconv.Itoa(page))
+	return r.URL.Path + "?" + values.Encode()
+}
 
-	RespondSuccess(w, h.logger, http.StatusOK, "Products retrieved successfully", products, pagination)
+// cursorLink returns the request's path and query continuing at the cursor
+func cursorLink(r *http.Request, cursor string) string {
+	values := r.URL.Query()
+	values.Del("page")
+	values.Set("cursor", cursor)
+	return r.URL.Path + "?" + values.Encode()
 }
@@ -8,8 +8,10 @@ import (
 // Router handles HTTP routing configuration
 type Router struct {
 	productHandler *ProductHandler
+	domainHandler  *DomainHandler
 	crawlerHandler *CrawlerHandler
 	sseHandler     *SSEHandler
+	adminHandler   *AdminHandler
 	logger         ports.Logger
 }
 
@@ -19,15 +21,19 @@ func (rw *responseWriter) WriteHeader(code int) {
 }
 
 // NewRouter creates a new router with the given dependencies
-func NewRouter(productService ports.ProductService, sseService ports.SSEService, logger ports.Logger) *Router {
+func NewRouter(productService ports.ProductService, sseService ports.SSEService, proxyMonitor ports.ProxyMonitor, cacheAdmin ports.CacheAdmin, logger ports.Logger) *Router {
 	productHandler := NewProductHandler(productService, logger)
+	domainHandler := NewDomainHandler(productService, logger)
 	sseHandler := NewSSEHandler(sseService, logger)
-	crawlerHandler := NewCrawlerHandler(productService, logger) // Create a new handler
+	crawlerHandler := NewCrawlerHandler(productService, cacheAdmin, logger) // Create a new handler
+	adminHandler := NewAdminHandler(proxyMonitor, cacheAdmin, logger)
 
 	return &Router{
 		productHandler: productHandler,
+		domainHandler:  domainHandler,
 		crawlerHandler: crawlerHandler, // Add to router
 		sseHandler:     sseHandler,
+		adminHandler:   adminHandler,
 		logger:         logger,
 	}
 }
@@ -41,11 +47,22 @@ func (r *Router) SetupRoutes() http.Handler {
 
 	// Product endpoints
 	mux.HandleFunc("GET /api/v1/products", r.productHandler.GetProduct)
+	mux.HandleFunc("GET /api/v1/products/{id}", r.productHandler.GetProductByID)
+
+	// Domain endpoints
+	mux.HandleFunc("GET /api/v1/domains", r.domainHandler.ListDomains)
+	mux.HandleFunc("GET /api/v1/domains/{domain}/stats", r.domainHandler.GetDomainStats)
 
 	// SSE endpoints
 	mux.HandleFunc("GET /api/v1/sse", r.sseHandler.HandleSSE)
 	mux.HandleFunc("GET /api/v1/sse/status", r.sseHandler.GetSSEStatus)
 
+	// Admin endpoints
+	mux.HandleFunc("GET /api/v1/admin/proxies", r.adminHandler.GetProxyHealth)
+	mux.HandleFunc("GET /api/v1/admin/cache", r.adminHandler.GetCacheEntry)
+	mux.HandleFunc("DELETE /api/v1/admin/cache", r.adminHandler.DeleteCacheEntries)
+	mux.HandleFunc("GET /api/v1/admin/cache/stats", r.adminHandler.GetCacheStats)
+
 	// Apply a middleware pipeline
 	return r.pipeline(mux, r.loggingMiddleware, r.corsMiddleware)
 }
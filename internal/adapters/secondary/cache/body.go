@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"bytes"
+	"io"
+
+	"web-crawler-go/internal/core/ports"
+)
+
+// bufferedBody returns cached bytes to callers without another copy
+type bufferedBody struct {
+	*bytes.Reader
+	data []byte
+}
+
+func newBufferedBody(data []byte) *bufferedBody {
+	return &bufferedBody{Reader: bytes.NewReader(data), data: data}
+}
+
+// Close implements io.Closer
+func (b *bufferedBody) Close() error {
+	return nil
+}
+
+// Bytes implements ports.BufferedBody
+func (b *bufferedBody) Bytes() []byte {
+	return b.data[len(b.data)-b.Len():]
+}
+
+// storedBody is a cached body returned together with the expiry and tags of its entry
+type storedBody struct {
+	*bufferedBody
+	storage ports.CacheEntryStorage
+}
+
+// CacheEntryStorage implements ports.CacheEntryStorageProvider
+func (b *storedBody) CacheEntryStorage() ports.CacheEntryStorage {
+	return b.storage
+}
+
+// metadataBody is a stored body that also carries its response metadata
+type metadataBody struct {
+	*storedBody
+	metadata ports.CacheEntryMetadata
+}
+
+// CacheEntryMetadata implements ports.CacheEntryMetadataProvider
+func (b *metadataBody) CacheEntryMetadata() ports.CacheEntryMetadata {
+	return b.metadata
+}
+
+// newEntryBody returns a cached value with the storage details of its entry and,
+// when not nil, its response metadata
+func newEntryBody(data []byte, metadata *ports.CacheEntryMetadata, storage ports.CacheEntryStorage) io.ReadCloser {
+	body := &storedBody{bufferedBody: newBufferedBody(data), storage: storage}
+	if metadata != nil {
+		return &metadataBody{storedBody: body, metadata: *metadata}
+	}
+	return body
+}
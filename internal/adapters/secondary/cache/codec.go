@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"web-crawler-go/internal/core/ports"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec selects how values are compressed before they are stored
+type Codec string
+
+const (
+	CodecNone Codec = "none"
+	CodecGzip Codec = "gzip"
+	CodecZstd Codec = "zstd"
+)
+
+// Encoded values start with a two byte header: valueMarker, which never starts an
+// HTML, XML or JSON document, followed by the format. Values without the marker were
+// stored before compression was introduced and are returned unchanged. When the
+// format has flagMetadata set, the header is followed by the length of a JSON
+// valueMetadata as a uvarint and the metadata itself, uncompressed.
+const (
+	valueMarker byte = 0x00
+
+	formatRaw  byte = 0x01
+	formatGzip byte = 0x02
+	formatZstd byte = 0x03
+
+	flagMetadata byte = 0x80
+)
+
+// valueMetadata is the metadata block of a value: the response metadata, when there
+// is any, and the tags the value was stored with. Blocks written before tags were
+// added hold only the response metadata and decode the same way.
+type valueMetadata struct {
+	*ports.CacheEntryMetadata
+	Tags []string `json:"tags,omitempty"`
+}
+
+func (m valueMetadata) empty() bool {
+	return m.CacheEntryMetadata == nil && len(m.Tags) == 0
+}
+
+// minCompressSize is the smallest value worth compressing
+const minCompressSize = 512
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// ParseCodec validates a codec name from configuration
+func ParseCodec(name string) (Codec, error) {
+	switch codec := Codec(name); codec {
+	case CodecNone, CodecGzip, CodecZstd:
+		return codec, nil
+	case "":
+		return CodecNone, nil
+	default:
+		return "", fmt.Errorf("unknown cache codec %q", name)
+	}
+}
+
+// encodeValue compresses data with codec and prepends the header and, when not empty,
+// the metadata. Small values, and values that do not shrink, are stored uncompressed.
+func encodeValue(codec Codec, data []byte, metadata valueMetadata) ([]byte, error) {
+	format := formatRaw
+	payload := data
+
+	if len(data) >= minCompressSize {
+		var compressed []byte
+		switch codec {
+		case CodecGzip:
+			var buf bytes.Buffer
+			writer := gzip.NewWriter(&buf)
+			if _, err := writer.Write(data); err != nil {
+				return nil, err
+			}
+			if err := writer.Close(); err != nil {
+				return nil, err
+			}
+			compressed = buf.Bytes()
+			format = formatGzip
+		case CodecZstd:
+			compressed = zstdEncoder.EncodeAll(data, make([]byte, 0, len(data)/4))
+			format = formatZstd
+		}
+		if compressed != nil && len(compressed) < len(data) {
+			payload = compressed
+		} else {
+			format = formatRaw
+		}
+	}
+
+	var metadataJSON []byte
+	if !metadata.empty() {
+		var err error
+		if metadataJSON, err = json.Marshal(metadata); err != nil {
+			return nil, fmt.Errorf("failed to encode cache entry metadata: %w", err)
+		}
+		format |= flagMetadata
+	}
+
+	encoded := make([]byte, 0, len(payload)+len(metadataJSON)+2+binary.MaxVarintLen64)
+	encoded = append(encoded, valueMarker, format)
+	if metadataJSON != nil {
+		encoded = binary.AppendUvarint(encoded, uint64(len(metadataJSON)))
+		encoded = append(encoded, metadataJSON...)
+	}
+	return append(encoded, payload...), nil
+}
+
+// decodeValue reverses encodeValue, passing through values written without a header.
+// The metadata is empty when the value was stored without it.
+func decodeValue(stored []byte) ([]byte, valueMetadata, error) {
+	var metadata valueMetadata
+	if len(stored) < 2 || stored[0] != valueMarker {
+		return stored, metadata, nil
+	}
+
+	format := stored[1]
+	payload := stored[2:]
+
+	if format&flagMetadata != 0 {
+		length, n := binary.Uvarint(payload)
+		if n <= 0 || length > uint64(len(payload)-n) {
+			return nil, metadata, fmt.Errorf("truncated cache entry metadata")
+		}
+		if err := json.Unmarshal(payload[n:n+int(length)], &metadata); err != nil {
+			return nil, metadata, fmt.Errorf("failed to decode cache entry metadata: %w", err)
+		}
+		payload = payload[n+int(length):]
+		format &^= flagMetadata
+	}
+
+	switch format {
+	case formatRaw:
+		return payload, metadata, nil
+	case formatGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, metadata, fmt.Errorf("failed to open gzip value: %w", err)
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, metadata, err
+		}
+		return data, metadata, nil
+	case formatZstd:
+		data, err := zstdDecoder.DecodeAll(payload, nil)
+		if err != nil {
+			return nil, metadata, err
+		}
+		return data, metadata, nil
+	default:
+		return nil, metadata, fmt.Errorf("unknown cached value format %d", stored[1])
+	}
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"web-crawler-go/internal/core/ports"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	small := []byte("<html></html>")
+	large := []byte(strings.Repeat("<li>Oolong tea 450 TWD</li>", 200))
+	metadata := valueMetadata{
+		CacheEntryMetadata: &ports.CacheEntryMetadata{URL: "https://shop.example.tw/", StatusCode: 200},
+		Tags:               []string{"domain:shop.example.tw"},
+	}
+
+	tests := []struct {
+		name       string
+		codec      Codec
+		data       []byte
+		metadata   valueMetadata
+		wantFormat byte
+	}{
+		{name: "none", codec: CodecNone, data: large, wantFormat: formatRaw},
+		{name: "gzip", codec: CodecGzip, data: large, wantFormat: formatGzip},
+		{name: "zstd", codec: CodecZstd, data: large, wantFormat: formatZstd},
+		{name: "small values stay raw", codec: CodecZstd, data: small, wantFormat: formatRaw},
+		{name: "zstd with metadata", codec: CodecZstd, data: large, metadata: metadata, wantFormat: formatZstd | flagMetadata},
+		{name: "raw with metadata", codec: CodecNone, data: small, metadata: metadata, wantFormat: formatRaw | flagMetadata},
+		{name: "tags only", codec: CodecGzip, data: large, metadata: valueMetadata{Tags: metadata.Tags}, wantFormat: formatGzip | flagMetadata},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := encodeValue(tt.codec, tt.data, tt.metadata)
+			if err != nil {
+				t.Fatalf("encodeValue: %v", err)
+			}
+			if encoded[0] != valueMarker || encoded[1] != tt.wantFormat {
+				t.Errorf("header = %#x %#x, want %#x %#x", encoded[0], encoded[1], valueMarker, tt.wantFormat)
+			}
+			if tt.wantFormat&^flagMetadata != formatRaw && len(encoded) >= len(tt.data) {
+				t.Errorf("compressed value is %d bytes, want fewer than %d", len(encoded), len(tt.data))
+			}
+
+			data, decoded, err := decodeValue(encoded)
+			if err != nil {
+				t.Fatalf("decodeValue: %v", err)
+			}
+			if !bytes.Equal(data, tt.data) {
+				t.Errorf("decoded %d bytes, want the original %d", len(data), len(tt.data))
+			}
+			if !slices.Equal(decoded.Tags, tt.metadata.Tags) {
+				t.Errorf("tags = %v, want %v", decoded.Tags, tt.metadata.Tags)
+			}
+			if (decoded.CacheEntryMetadata == nil) != (tt.metadata.CacheEntryMetadata == nil) {
+				t.Fatalf("response metadata = %+v, want %+v", decoded.CacheEntryMetadata, tt.metadata.CacheEntryMetadata)
+			}
+			if decoded.CacheEntryMetadata != nil && decoded.URL != tt.metadata.URL {
+				t.Errorf("response URL = %q, want %q", decoded.URL, tt.metadata.URL)
+			}
+		})
+	}
+}
+
+func TestCodecDecodesLegacyValues(t *testing.T) {
+	legacyMetadata, _ := json.Marshal(ports.CacheEntryMetadata{URL: "https://shop.example.tw/", StatusCode: 200})
+
+	tests := []struct {
+		name     string
+		stored   []byte
+		wantData string
+		wantURL  string
+	}{
+		// Written before values had a header
+		{name: "html", stored: []byte("<html>舊的</html>"), wantData: "<html>舊的</html>"},
+		{name: "json", stored: []byte(`{"products":[]}`), wantData: `{"products":[]}`},
+		{name: "single byte", stored: []byte("x"), wantData: "x"},
+		// Written before tags were added to the metadata block
+		{
+			name:     "response metadata without tags",
+			stored:   append(append(binary.AppendUvarint([]byte{valueMarker, formatRaw | flagMetadata}, uint64(len(legacyMetadata))), legacyMetadata...), "<html></html>"...),
+			wantData: "<html></html>",
+			wantURL:  "https://shop.example.tw/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, metadata, err := decodeValue(tt.stored)
+			if err != nil {
+				t.Fatalf("decodeValue: %v", err)
+			}
+			if string(data) != tt.wantData {
+				t.Errorf("data = %q, want %q", data, tt.wantData)
+			}
+			if len(metadata.Tags) != 0 {
+				t.Errorf("tags = %v, want none", metadata.Tags)
+			}
+			gotURL := ""
+			if metadata.CacheEntryMetadata != nil {
+				gotURL = metadata.URL
+			}
+			if gotURL != tt.wantURL {
+				t.Errorf("response URL = %q, want %q", gotURL, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestCodecRejectsCorruptValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		stored []byte
+	}{
+		{name: "unknown format", stored: []byte{valueMarker, 0x7f, 'x'}},
+		{name: "truncated metadata", stored: []byte{valueMarker, formatRaw | flagMetadata, 10, '{'}},
+		{name: "bad gzip", stored: []byte{valueMarker, formatGzip, 'x', 'y'}},
+		{name: "bad zstd", stored: []byte{valueMarker, formatZstd, 'x', 'y'}},
+	}
+	for _, tt := range tests {
+		if _, _, err := decodeValue(tt.stored); err == nil {
+			t.Errorf("%s: decodeValue succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func TestParseCodec(t *testing.T) {
+	for name, want := range map[string]Codec{"": CodecNone, "none": CodecNone, "gzip": CodecGzip, "zstd": CodecZstd} {
+		if got, err := ParseCodec(name); err != nil || got != want {
+			t.Errorf("ParseCodec(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseCodec("brotli"); err == nil {
+		t.Errorf("ParseCodec accepted an unknown codec")
+	}
+}
@@ -0,0 +1,477 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"web-crawler-go/internal/core/ports"
+)
+
+const (
+	bodyFileExt     = ".body"
+	metadataFileExt = ".json"
+	// cleanupTarget is the fraction of maxBytes a size cleanup shrinks the cache to,
+	// so a full cache is not cleaned again on every write
+	cleanupTarget = 0.9
+	// writeGracePeriod is how long a body may go without a sidecar, or a temporary
+	// file may stay around, before it is taken for what a failed write left behind.
+	// Set writes the body before the sidecar.
+	writeGracePeriod = time.Minute
+	tempFilePattern  = ".tmp-*"
+)
+
+// fileSidecar is the metadata file stored next to each cached body
+type fileSidecar struct {
+	Key       string                    `json:"key"`
+	Size      int64                     `json:"size"`
+	StoredAt  time.Time                 `json:"stored_at"`
+	ExpiresAt time.Time                 `json:"expires_at,omitzero"`
+	Tags      []string                  `json:"tags,omitempty"`
+	Response  *ports.CacheEntryMetadata `json:"response,omitempty"`
+}
+
+func (s *fileSidecar) expired(now time.Time) bool {
+	return !s.ExpiresAt.IsZero() && !now.Before(s.ExpiresAt)
+}
+
+// FileSystemCache implements the CacheService interface by storing bodies on disk.
+// Files live in a directory tree sharded by the key hash, each body with a JSON
+// sidecar describing where it came from. The body file's modification time is
+// bumped on every read and serves as the access time for LRU cleanup.
+type FileSystemCache struct {
+	dir      string
+	maxBytes int64
+
+	size        atomic.Int64
+	entries     atomic.Int64
+	hits        atomic.Int64
+	misses      atomic.Int64
+	evictions   atomic.Int64
+	expirations atomic.Int64
+
+	cleanupMu sync.Mutex
+	stop      chan struct{}
+	closeOnce sync.Once
+	now       func() time.Time
+}
+
+// NewFileSystemCache creates a disk cache rooted at dir holding at most maxBytes of
+// bodies (zero means unbounded). Expired entries are removed every sweepInterval.
+func NewFileSystemCache(dir string, maxBytes int64, sweepInterval time.Duration) (*FileSystemCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	c := &FileSystemCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		stop:     make(chan struct{}),
+		now:      time.Now,
+	}
+
+	// Pick up the size of whatever an earlier run left behind
+	files, err := c.scan()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan cache directory: %w", err)
+	}
+	c.resetCounts(files)
+
+	if sweepInterval > 0 {
+		go c.sweepLoop(sweepInterval)
+	}
+
+	return c, nil
+}
+
+// Get retrieves data from the cache for the given key
+func (c *FileSystemCache) Get(ctx context.Context, key string) (io.ReadCloser, bool, error) {
+	bodyPath, metadataPath := c.paths(key)
+
+	sidecar, err := readSidecar(metadataPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		c.misses.Add(1)
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if sidecar.expired(c.now()) {
+		c.remove(bodyPath, metadataPath)
+		c.expirations.Add(1)
+		c.misses.Add(1)
+		return nil, false, nil
+	}
+
+	data, err := os.ReadFile(bodyPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		// A sidecar without its body is a leftover from an interrupted write
+		c.remove(bodyPath, metadataPath)
+		c.misses.Add(1)
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Record the access for LRU cleanup
+	now := c.now()
+	_ = os.Chtimes(bodyPath, now, now)
+
+	c.hits.Add(1)
+	return newEntryBody(data, sidecar.Response, ports.CacheEntryStorage{ExpiresAt: sidecar.ExpiresAt, Tags: sidecar.Tags}), true, nil
+}
+
+// Set stores data in the cache with the given key and expiration time.
+// Response metadata attached with ports.WithCacheEntryMetadata and tags attached
+// with ports.WithCacheTags are written to the sidecar.
+func (c *FileSystemCache) Set(ctx context.Context, key string, value io.ReadCloser, expiration time.Duration) error {
+	data, err := ports.ReadAll(value)
+	if err != nil {
+		return err
+	}
+
+	bodyPath, metadataPath := c.paths(key)
+	if err := os.MkdirAll(filepath.Dir(bodyPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create cache shard: %w", err)
+	}
+
+	now := c.now()
+	sidecar := fileSidecar{
+		Key:      key,
+		Size:     int64(len(data)),
+		StoredAt: now,
+		Tags:     ports.CacheTagsFromContext(ctx),
+	}
+	if expiration > 0 {
+		sidecar.ExpiresAt = now.Add(expiration)
+	}
+	if metadata, ok := ports.CacheEntryMetadataFromContext(ctx); ok {
+		sidecar.Response = &metadata
+	}
+	sidecarBytes, err := json.MarshalIndent(sidecar, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cache metadata: %w", err)
+	}
+
+	previousSize := fileSize(bodyPath)
+
+	// The body goes first so a reader never finds a sidecar pointing at a partial body
+	if err := writeFileAtomic(bodyPath, data); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(metadataPath, sidecarBytes); err != nil {
+		return err
+	}
+
+	if previousSize < 0 {
+		c.entries.Add(1)
+		previousSize = 0
+	}
+	if c.size.Add(int64(len(data))-previousSize) > c.maxBytes && c.maxBytes > 0 {
+		c.cleanup()
+	}
+
+	return nil
+}
+
+// Delete removes data from the cache for the given key
+func (c *FileSystemCache) Delete(ctx context.Context, key string) error {
+	bodyPath, metadataPath := c.paths(key)
+	c.remove(bodyPath, metadataPath)
+	return nil
+}
+
+// KeysByTag implements ports.TaggedCache. It reads every sidecar, so it is meant
+// for administrative purges rather than the fetch path.
+func (c *FileSystemCache) KeysByTag(ctx context.Context, tag string) ([]string, error) {
+	var keys []string
+	err := c.eachTagged(tag, func(sidecar *fileSidecar, bodyPath, metadataPath string) {
+		keys = append(keys, sidecar.Key)
+	})
+	return keys, err
+}
+
+// DeleteByTag implements ports.TaggedCache
+func (c *FileSystemCache) DeleteByTag(ctx context.Context, tag string) (int, error) {
+	deleted := 0
+	err := c.eachTagged(tag, func(sidecar *fileSidecar, bodyPath, metadataPath string) {
+		c.remove(bodyPath, metadataPath)
+		deleted++
+	})
+	return deleted, err
+}
+
+// eachTagged calls fn for every stored entry carrying tag
+func (c *FileSystemCache) eachTagged(tag string, fn func(sidecar *fileSidecar, bodyPath, metadataPath string)) error {
+	files, err := c.scan()
+	if err != nil {
+		return fmt.Errorf("failed to scan cache directory: %w", err)
+	}
+
+	for _, file := range files {
+		metadataPath := strings.TrimSuffix(file.bodyPath, bodyFileExt) + metadataFileExt
+		sidecar, err := readSidecar(metadataPath)
+		if err != nil {
+			continue
+		}
+		if slices.Contains(sidecar.Tags, tag) {
+			fn(sidecar, file.bodyPath, metadataPath)
+		}
+	}
+	return nil
+}
+
+// Stats implements ports.CacheStatsProvider
+func (c *FileSystemCache) Stats(ctx context.Context) (ports.CacheStats, error) {
+	return ports.CacheStats{
+		Backend:     "disk",
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Entries:     c.entries.Load(),
+		SizeBytes:   c.size.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+	}, nil
+}
+
+// Close stops the background sweeper
+func (c *FileSystemCache) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+	})
+	return nil
+}
+
+// paths returns the body and sidecar paths for a key. Keys of the form
+// "<prefix>:<sha256 hex>", as generated by the fetcher, are sharded by their hash;
+// any other key is hashed first.
+func (c *FileSystemCache) paths(key string) (string, string) {
+	prefix, hash, ok := strings.Cut(key, ":")
+	if !ok || !isSHA256Hex(hash) {
+		sum := sha256.Sum256([]byte(key))
+		prefix, hash = "key", hex.EncodeToString(sum[:])
+	}
+
+	base := filepath.Join(c.dir, hash[0:2], hash[2:4], prefix+"_"+hash)
+	return base + bodyFileExt, base + metadataFileExt
+}
+
+// remove deletes an entry's files and updates the counters
+func (c *FileSystemCache) remove(bodyPath, metadataPath string) {
+	size := fileSize(bodyPath)
+	if err := os.Remove(bodyPath); err == nil {
+		c.entries.Add(-1)
+		c.size.Add(-size)
+	}
+	_ = os.Remove(metadataPath)
+}
+
+// cachedFile is one entry found while scanning the cache directory
+type cachedFile struct {
+	bodyPath   string
+	accessedAt time.Time
+	size       int64
+}
+
+// scan lists every cached body in the directory tree, removing temporary files
+// that interrupted writes left behind
+func (c *FileSystemCache) scan() ([]cachedFile, error) {
+	var files []cachedFile
+	now := c.now()
+	err := filepath.WalkDir(c.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		isTemp, _ := filepath.Match(tempFilePattern, entry.Name())
+		if !isTemp && filepath.Ext(path) != bodyFileExt {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// Removed while walking
+			return nil
+		}
+		if isTemp {
+			if now.Sub(info.ModTime()) > writeGracePeriod {
+				_ = os.Remove(path)
+			}
+			return nil
+		}
+		files = append(files, cachedFile{bodyPath: path, accessedAt: info.ModTime(), size: info.Size()})
+		return nil
+	})
+	return files, err
+}
+
+// expired reports whether a scanned entry should be removed: its sidecar says it
+// has expired, or it has none and is older than a write in progress could be
+func (c *FileSystemCache) expired(file cachedFile, metadataPath string, now time.Time) bool {
+	sidecar, err := readSidecar(metadataPath)
+	if err != nil {
+		return now.Sub(file.accessedAt) > writeGracePeriod
+	}
+	return sidecar.expired(now)
+}
+
+// resetCounts replaces the running size and entry counters with a fresh scan
+func (c *FileSystemCache) resetCounts(files []cachedFile) {
+	var total int64
+	for _, file := range files {
+		total += file.size
+	}
+	c.size.Store(total)
+	c.entries.Store(int64(len(files)))
+}
+
+// sweepLoop periodically removes expired entries
+func (c *FileSystemCache) sweepLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.sweep()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// sweep removes every expired entry
+func (c *FileSystemCache) sweep() {
+	c.cleanupMu.Lock()
+	defer c.cleanupMu.Unlock()
+
+	files, err := c.scan()
+	if err != nil {
+		return
+	}
+
+	now := c.now()
+	for _, file := range files {
+		metadataPath := strings.TrimSuffix(file.bodyPath, bodyFileExt) + metadataFileExt
+		if c.expired(file, metadataPath, now) {
+			c.remove(file.bodyPath, metadataPath)
+			c.expirations.Add(1)
+		}
+	}
+}
+
+// cleanup removes expired entries and then the least recently used ones until
+// the cache is back under its size cap. Concurrent calls are skipped.
+func (c *FileSystemCache) cleanup() {
+	if !c.cleanupMu.TryLock() {
+		return
+	}
+	defer c.cleanupMu.Unlock()
+
+	files, err := c.scan()
+	if err != nil {
+		return
+	}
+	c.resetCounts(files)
+
+	now := c.now()
+	live := files[:0]
+	for _, file := range files {
+		metadataPath := strings.TrimSuffix(file.bodyPath, bodyFileExt) + metadataFileExt
+		if c.expired(file, metadataPath, now) {
+			c.remove(file.bodyPath, metadataPath)
+			c.expirations.Add(1)
+			continue
+		}
+		live = append(live, file)
+	}
+
+	target := int64(float64(c.maxBytes) * cleanupTarget)
+	sort.Slice(live, func(i, j int) bool {
+		return live[i].accessedAt.Before(live[j].accessedAt)
+	})
+	for _, file := range live {
+		if c.size.Load() <= target {
+			break
+		}
+		c.remove(file.bodyPath, strings.TrimSuffix(file.bodyPath, bodyFileExt)+metadataFileExt)
+		c.evictions.Add(1)
+	}
+}
+
+// readSidecar loads an entry's metadata file
+func readSidecar(path string) (*fileSidecar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sidecar fileSidecar
+	if err := json.Unmarshal(data, &sidecar); err != nil {
+		return nil, fmt.Errorf("failed to decode cache metadata %s: %w", path, err)
+	}
+	return &sidecar, nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), tempFilePattern)
+	if err != nil {
+		return fmt.Errorf("failed to create temporary cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to move cache file into place: %w", err)
+	}
+	return nil
+}
+
+// fileSize returns the size of a file, or -1 when it does not exist
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return -1
+	}
+	return info.Size()
+}
+
+func isSHA256Hex(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// Ensure FileSystemCache implements CacheService, CacheStatsProvider and TaggedCache
+var (
+	_ ports.CacheService       = (*FileSystemCache)(nil)
+	_ ports.CacheStatsProvider = (*FileSystemCache)(nil)
+	_ ports.TaggedCache        = (*FileSystemCache)(nil)
+)
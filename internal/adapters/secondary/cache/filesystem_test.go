@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"web-crawler-go/internal/core/ports"
+)
+
+// fetcherKey is a key in the form the fetcher generates
+const fetcherKey = "url:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+func newTestFileSystemCache(t *testing.T, maxBytes int64) *FileSystemCache {
+	t.Helper()
+	c, err := NewFileSystemCache(t.TempDir(), maxBytes, 0)
+	if err != nil {
+		t.Fatalf("NewFileSystemCache: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// age sets a file's modification time to d before now
+func age(t *testing.T, path string, d time.Duration) {
+	t.Helper()
+	past := time.Now().Add(-d)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileSystemCacheStoresShardedBodyAndSidecar(t *testing.T) {
+	c := newTestFileSystemCache(t, 0)
+	metadata := ports.CacheEntryMetadata{URL: "https://shop.example.tw/", StatusCode: 200}
+	ctx := ports.WithCacheTags(ports.WithCacheEntryMetadata(context.Background(), metadata), "domain:shop.example.tw")
+	if err := c.Set(ctx, fetcherKey, value("<html></html>"), time.Hour); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	bodyPath, metadataPath := c.paths(fetcherKey)
+	wantBody := filepath.Join(c.dir, "9f", "86", "url_9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08.body")
+	if bodyPath != wantBody {
+		t.Errorf("body path = %s, want %s", bodyPath, wantBody)
+	}
+	sidecar, err := readSidecar(metadataPath)
+	if err != nil {
+		t.Fatalf("readSidecar: %v", err)
+	}
+	if sidecar.Key != fetcherKey || sidecar.Size != 13 || sidecar.ExpiresAt.IsZero() ||
+		sidecar.Response == nil || sidecar.Response.URL != metadata.URL || len(sidecar.Tags) != 1 {
+		t.Errorf("sidecar = %+v, want the key, size, expiry, response and tag", sidecar)
+	}
+
+	if got, found := getString(t, c, fetcherKey); !found || got != "<html></html>" {
+		t.Errorf("Get = %q, %v, want the body", got, found)
+	}
+}
+
+func TestFileSystemCacheExpiry(t *testing.T) {
+	c := newTestFileSystemCache(t, 0)
+	ctx := context.Background()
+	c.Set(ctx, "read", value("read"), time.Minute)
+	c.Set(ctx, "swept", value("swept"), time.Minute)
+	c.Set(ctx, "forever", value("forever"), 0)
+
+	c.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, found := getString(t, c, "read"); found {
+		t.Errorf("expired entry was served")
+	}
+	c.sweep()
+	if _, found := getString(t, c, "forever"); !found {
+		t.Errorf("an entry without expiry was removed")
+	}
+	bodyPath, _ := c.paths("swept")
+	if exists(bodyPath) {
+		t.Errorf("sweep left the expired body behind")
+	}
+
+	stats, _ := c.Stats(ctx)
+	if stats.Entries != 1 || stats.SizeBytes != int64(len("forever")) || stats.Expirations != 2 {
+		t.Errorf("stats = %+v, want 1 entry and 2 expirations", stats)
+	}
+}
+
+// A body whose sidecar is not written yet belongs to a Set in progress; the sweep
+// must leave it alone until the grace period has passed
+func TestFileSystemCacheSweepSparesWritesInProgress(t *testing.T) {
+	c := newTestFileSystemCache(t, 0)
+	ctx := context.Background()
+	if err := c.Set(ctx, fetcherKey, value("<html></html>"), time.Hour); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	bodyPath, metadataPath := c.paths(fetcherKey)
+	if err := os.Remove(metadataPath); err != nil {
+		t.Fatal(err)
+	}
+
+	c.sweep()
+	if !exists(bodyPath) {
+		t.Fatalf("sweep removed a body still within the write grace period")
+	}
+
+	age(t, bodyPath, writeGracePeriod+time.Second)
+	c.sweep()
+	if exists(bodyPath) {
+		t.Errorf("sweep kept a body without a sidecar past the grace period")
+	}
+	if stats, _ := c.Stats(ctx); stats.Entries != 0 || stats.SizeBytes != 0 {
+		t.Errorf("stats = %+v, want the orphan no longer counted", stats)
+	}
+}
+
+func TestFileSystemCacheRemovesStaleTempFiles(t *testing.T) {
+	c := newTestFileSystemCache(t, 0)
+	shard := filepath.Join(c.dir, "ab", "cd")
+	if err := os.MkdirAll(shard, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	fresh := filepath.Join(shard, ".tmp-fresh")
+	stale := filepath.Join(shard, ".tmp-stale")
+	for _, path := range []string{fresh, stale} {
+		if err := os.WriteFile(path, []byte("partial"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	age(t, stale, writeGracePeriod+time.Second)
+
+	c.sweep()
+	if !exists(fresh) {
+		t.Errorf("sweep removed a temporary file a write may still be using")
+	}
+	if exists(stale) {
+		t.Errorf("sweep kept a temporary file left by an interrupted write")
+	}
+	if stats, _ := c.Stats(context.Background()); stats.Entries != 0 || stats.SizeBytes != 0 {
+		t.Errorf("stats = %+v, want temporary files not counted", stats)
+	}
+}
+
+func TestFileSystemCacheEvictsLeastRecentlyRead(t *testing.T) {
+	c := newTestFileSystemCache(t, 20)
+	ctx := context.Background()
+	for i, key := range []string{"a", "b", "c"} {
+		c.Set(ctx, key, value(strings.Repeat(key, 6)), 0)
+		bodyPath, _ := c.paths(key)
+		age(t, bodyPath, time.Duration(10-i)*time.Minute)
+	}
+	// Reading a makes b the least recently used entry
+	getString(t, c, "a")
+
+	c.Set(ctx, "d", value("dddddd"), 0)
+	if _, found := getString(t, c, "b"); found {
+		t.Errorf("b was kept, want it evicted")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, found := getString(t, c, key); !found {
+			t.Errorf("%s was evicted, want it kept", key)
+		}
+	}
+	if stats, _ := c.Stats(ctx); stats.SizeBytes != 18 || stats.Evictions != 1 {
+		t.Errorf("stats = %+v, want 18 bytes and 1 eviction", stats)
+	}
+}
+
+func TestFileSystemCacheCountsExistingEntriesOnStart(t *testing.T) {
+	dir := t.TempDir()
+	first, err := NewFileSystemCache(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("NewFileSystemCache: %v", err)
+	}
+	first.Set(context.Background(), "a", value("aaaa"), 0)
+	first.Set(context.Background(), "b", value("bb"), 0)
+	first.Close()
+
+	second, err := NewFileSystemCache(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("NewFileSystemCache: %v", err)
+	}
+	defer second.Close()
+	if stats, _ := second.Stats(context.Background()); stats.Entries != 2 || stats.SizeBytes != 6 {
+		t.Errorf("stats = %+v, want 2 entries of 6 bytes", stats)
+	}
+}
@@ -0,0 +1,252 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"web-crawler-go/internal/core/ports"
+)
+
+// memoryEntry is a cached value held in the LRU list
+type memoryEntry struct {
+	key       string
+	data      []byte
+	tags      []string
+	metadata  *ports.CacheEntryMetadata
+	expiresAt time.Time // Zero means the entry never expires
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// MemoryCache implements the CacheService interface with an in-process LRU
+// bounded by the total size of the stored values.
+type MemoryCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	entries  map[string]*list.Element
+	lru      *list.List // Front is the most recently used entry
+	tags     map[string]map[string]struct{}
+
+	hits        int64
+	misses      int64
+	evictions   int64
+	expirations int64
+
+	stop      chan struct{}
+	closeOnce sync.Once
+	now       func() time.Time
+}
+
+// NewMemoryCache creates an in-memory cache holding at most maxBytes of values.
+// Expired entries are swept every sweepInterval; zero disables the sweeper and
+// leaves expired entries to be dropped when they are next read or evicted.
+func NewMemoryCache(maxBytes int64, sweepInterval time.Duration) *MemoryCache {
+	c := &MemoryCache{
+		maxBytes: maxBytes,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+		tags:     make(map[string]map[string]struct{}),
+		stop:     make(chan struct{}),
+		now:      time.Now,
+	}
+
+	if sweepInterval > 0 {
+		go c.sweepLoop(sweepInterval)
+	}
+
+	return c
+}
+
+// Get retrieves data from the cache for the given key
+func (c *MemoryCache) Get(ctx context.Context, key string) (io.ReadCloser, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false, nil
+	}
+
+	entry := element.Value.(*memoryEntry)
+	if entry.expired(c.now()) {
+		c.removeElement(element)
+		c.expirations++
+		c.misses++
+		return nil, false, nil
+	}
+
+	c.lru.MoveToFront(element)
+	c.hits++
+	return newEntryBody(entry.data, entry.metadata, ports.CacheEntryStorage{ExpiresAt: entry.expiresAt, Tags: entry.tags}), true, nil
+}
+
+// Set stores data in the cache with the given key and expiration time.
+// Values larger than the whole cache are not stored. Response metadata attached
+// with ports.WithCacheEntryMetadata is kept with the value, and tags attached with
+// ports.WithCacheTags are indexed for DeleteByTag.
+func (c *MemoryCache) Set(ctx context.Context, key string, value io.ReadCloser, expiration time.Duration) error {
+	// The buffer is kept as is; cached values are never modified
+	data, err := ports.ReadAll(value)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.removeElement(element)
+	}
+
+	if int64(len(data)) > c.maxBytes {
+		return nil
+	}
+
+	entry := &memoryEntry{key: key, data: data, tags: ports.CacheTagsFromContext(ctx)}
+	if metadata, ok := ports.CacheEntryMetadataFromContext(ctx); ok {
+		entry.metadata = &metadata
+	}
+	if expiration > 0 {
+		entry.expiresAt = c.now().Add(expiration)
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	c.size += int64(len(data))
+	for _, tag := range entry.tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = make(map[string]struct{})
+		}
+		c.tags[tag][key] = struct{}{}
+	}
+
+	// Evict least recently used entries until the new value fits
+	for c.size > c.maxBytes {
+		oldest := c.lru.Back()
+		if oldest == nil {
+			break
+		}
+		c.removeElement(oldest)
+		c.evictions++
+	}
+
+	return nil
+}
+
+// Delete removes data from the cache for the given key
+func (c *MemoryCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.removeElement(element)
+	}
+	return nil
+}
+
+// KeysByTag implements ports.TaggedCache
+func (c *MemoryCache) KeysByTag(ctx context.Context, tag string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]string, 0, len(c.tags[tag]))
+	for key := range c.tags[tag] {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// DeleteByTag implements ports.TaggedCache
+func (c *MemoryCache) DeleteByTag(ctx context.Context, tag string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	deleted := 0
+	for key := range c.tags[tag] {
+		if element, ok := c.entries[key]; ok {
+			c.removeElement(element)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Stats implements ports.CacheStatsProvider
+func (c *MemoryCache) Stats(ctx context.Context) (ports.CacheStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return ports.CacheStats{
+		Backend:     "memory",
+		Hits:        c.hits,
+		Misses:      c.misses,
+		Entries:     int64(len(c.entries)),
+		SizeBytes:   c.size,
+		Evictions:   c.evictions,
+		Expirations: c.expirations,
+	}, nil
+}
+
+// Close stops the background sweeper
+func (c *MemoryCache) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+	})
+	return nil
+}
+
+// sweepLoop periodically drops expired entries
+func (c *MemoryCache) sweepLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.sweep()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// sweep removes every expired entry
+func (c *MemoryCache) sweep() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for element := c.lru.Back(); element != nil; {
+		previous := element.Prev()
+		if element.Value.(*memoryEntry).expired(now) {
+			c.removeElement(element)
+			c.expirations++
+		}
+		element = previous
+	}
+}
+
+// removeElement unlinks an entry; the caller must hold the lock
+func (c *MemoryCache) removeElement(element *list.Element) {
+	entry := c.lru.Remove(element).(*memoryEntry)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.data))
+	for _, tag := range entry.tags {
+		delete(c.tags[tag], entry.key)
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
+
+// Ensure MemoryCache implements CacheService, CacheStatsProvider and TaggedCache
+var (
+	_ ports.CacheService       = (*MemoryCache)(nil)
+	_ ports.CacheStatsProvider = (*MemoryCache)(nil)
+	_ ports.TaggedCache        = (*MemoryCache)(nil)
+)
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"web-crawler-go/internal/core/ports"
+)
+
+// value wraps a string for CacheService.Set
+func value(s string) io.ReadCloser {
+	return io.NopCloser(strings.NewReader(s))
+}
+
+// getString reads a cached value, reporting whether it was found
+func getString(t *testing.T, cache ports.CacheService, key string) (string, bool) {
+	t.Helper()
+	body, found, err := cache.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	if !found {
+		return "", false
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("read %q: %v", key, err)
+	}
+	return string(data), true
+}
+
+func memoryStats(t *testing.T, c *MemoryCache) ports.CacheStats {
+	t.Helper()
+	stats, err := c.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	return stats
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(10, 0)
+	defer c.Close()
+
+	c.Set(ctx, "a", value("aaaa"), 0)
+	c.Set(ctx, "b", value("bbbb"), 0)
+	// Reading a makes b the least recently used entry
+	getString(t, c, "a")
+	c.Set(ctx, "c", value("cccc"), 0)
+
+	if _, found := getString(t, c, "b"); found {
+		t.Errorf("b was kept, want it evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if got, found := getString(t, c, key); !found || got != strings.Repeat(key, 4) {
+			t.Errorf("%s = %q, %v, want it kept", key, got, found)
+		}
+	}
+
+	stats := memoryStats(t, c)
+	if stats.Entries != 2 || stats.SizeBytes != 8 || stats.Evictions != 1 {
+		t.Errorf("stats = %+v, want 2 entries, 8 bytes and 1 eviction", stats)
+	}
+}
+
+func TestMemoryCacheByteAccounting(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(10, 0)
+	defer c.Close()
+
+	steps := []struct {
+		name     string
+		apply    func()
+		wantSize int64
+		wantKeys int64
+	}{
+		{name: "set", apply: func() { c.Set(ctx, "a", value("aaa"), 0) }, wantSize: 3, wantKeys: 1},
+		{name: "replace with a larger value", apply: func() { c.Set(ctx, "a", value("aaaaaa"), 0) }, wantSize: 6, wantKeys: 1},
+		{name: "second key", apply: func() { c.Set(ctx, "b", value("bb"), 0) }, wantSize: 8, wantKeys: 2},
+		{name: "value larger than the cache is not stored", apply: func() { c.Set(ctx, "big", value("0123456789x"), 0) }, wantSize: 8, wantKeys: 2},
+		{name: "exactly the cache size evicts the rest", apply: func() { c.Set(ctx, "full", value("0123456789"), 0) }, wantSize: 10, wantKeys: 1},
+		{name: "delete", apply: func() { c.Delete(ctx, "full") }, wantSize: 0, wantKeys: 0},
+	}
+	for _, step := range steps {
+		step.apply()
+		stats := memoryStats(t, c)
+		if stats.SizeBytes != step.wantSize || stats.Entries != step.wantKeys {
+			t.Errorf("%s: %d entries of %d bytes, want %d of %d", step.name, stats.Entries, stats.SizeBytes, step.wantKeys, step.wantSize)
+		}
+	}
+}
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(100, 0)
+	defer c.Close()
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "short", value("short"), time.Minute)
+	c.Set(ctx, "swept", value("swept"), time.Minute)
+	c.Set(ctx, "forever", value("forever"), 0)
+
+	now = now.Add(time.Minute)
+	if _, found := getString(t, c, "short"); found {
+		t.Errorf("short was served after it expired")
+	}
+	c.sweep()
+	if _, found := getString(t, c, "forever"); !found {
+		t.Errorf("an entry without expiry was dropped")
+	}
+
+	stats := memoryStats(t, c)
+	if stats.Entries != 1 || stats.SizeBytes != int64(len("forever")) || stats.Expirations != 2 {
+		t.Errorf("stats = %+v, want 1 entry and 2 expirations", stats)
+	}
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("hits %d and misses %d, want 1 and 1", stats.Hits, stats.Misses)
+	}
+}
+
+func TestMemoryCacheKeepsMetadataAndTags(t *testing.T) {
+	c := NewMemoryCache(100, 0)
+	defer c.Close()
+
+	metadata := ports.CacheEntryMetadata{URL: "https://shop.example.tw/", StatusCode: 200}
+	ctx := ports.WithCacheEntryMetadata(context.Background(), metadata)
+	ctx = ports.WithCacheTags(ctx, "domain:shop.example.tw")
+	c.Set(ctx, "home", value("<html></html>"), 0)
+	c.Set(context.Background(), "other", value("<html></html>"), 0)
+
+	body, found, err := c.Get(context.Background(), "home")
+	if err != nil || !found {
+		t.Fatalf("Get = %v, %v, want a hit", found, err)
+	}
+	provider, ok := body.(ports.CacheEntryMetadataProvider)
+	if !ok || provider.CacheEntryMetadata().URL != metadata.URL {
+		t.Errorf("body %T lost its metadata", body)
+	}
+
+	deleted, err := c.DeleteByTag(context.Background(), "domain:shop.example.tw")
+	if err != nil || deleted != 1 {
+		t.Errorf("DeleteByTag = %d, %v, want 1", deleted, err)
+	}
+	if _, found := getString(t, c, "home"); found {
+		t.Errorf("tagged entry survived DeleteByTag")
+	}
+	if _, found := getString(t, c, "other"); !found {
+		t.Errorf("untagged entry was deleted")
+	}
+}
@@ -1,10 +1,10 @@
 package cache
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"io"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -13,49 +13,131 @@ import (
 
 // RedisCache implements the CacheService interface using Redis
 type RedisCache struct {
-	client *redis.Client
+	client redis.UniversalClient
+	codec  Codec
+
+	hits   atomic.Int64
+	misses atomic.Int64
+	// Sizes of the values written by this process, before and after compression
+	uncompressedBytes atomic.Int64
+	storedBytes       atomic.Int64
 }
 
-// NewRedisCache creates a new Redis cache service
-func NewRedisCache(addr string, password string, db int) *RedisCache {
-	client := redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: password,
-		DB:       db,
-	})
+// NewRedisCache creates a new Redis cache service for a standalone, Sentinel or
+// Cluster deployment, compressing values with config.Codec
+func NewRedisCache(config RedisConfig) (*RedisCache, error) {
+	options, err := config.universalOptions()
+	if err != nil {
+		return nil, err
+	}
 
 	return &RedisCache{
-		client: client,
-	}
+		client: redis.NewUniversalClient(options),
+		codec:  config.Codec,
+	}, nil
+}
+
+// Close releases the connections to Redis
+func (c *RedisCache) Close() error {
+	return c.client.Close()
 }
 
-// Get retrieves data from the cache for the given key
+// Get retrieves data from the cache for the given key, along with its remaining
+// time to live and the tags stored in the value header
 func (c *RedisCache) Get(ctx context.Context, key string) (io.ReadCloser, bool, error) {
-	data, err := c.client.Get(ctx, key).Bytes()
+	var get *redis.StringCmd
+	var ttl *redis.DurationCmd
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		ttl = pipe.PTTL(ctx, key)
+		return nil
+	})
+	stored, getErr := get.Bytes()
+	if errors.Is(getErr, redis.Nil) {
+		// Key does not exist
+		c.misses.Add(1)
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	data, metadata, err := decodeValue(stored)
 	if err != nil {
-		if errors.Is(err, redis.Nil) {
-			// Key does not exist
-			return nil, false, nil
-		}
 		return nil, false, err
 	}
 
-	return io.NopCloser(bytes.NewReader(data)), true, nil
+	storage := ports.CacheEntryStorage{Tags: metadata.Tags}
+	// PTTL is negative for keys without expiry
+	if remaining := ttl.Val(); remaining > 0 {
+		storage.ExpiresAt = time.Now().Add(remaining)
+	}
+
+	c.hits.Add(1)
+	return newEntryBody(data, metadata.CacheEntryMetadata, storage), true, nil
 }
 
-// Set stores data in the cache with the given key and expiration time
+// tagSetPrefix prefixes the Redis sets listing the keys stored with each tag
+const tagSetPrefix = "tag:"
+
+// tagScript adds ARGV[1] to the tag set KEYS[1] and keeps the set alive as long as
+// its longest-lived key: a new set takes the key's TTL in milliseconds (ARGV[2]),
+// an existing one is only extended, and a key without expiry makes it persistent.
+var tagScript = redis.NewScript(`
+local existed = redis.call('EXISTS', KEYS[1]) == 1
+redis.call('SADD', KEYS[1], ARGV[1])
+local ttl = tonumber(ARGV[2])
+if ttl <= 0 then
+	return redis.call('PERSIST', KEYS[1])
+end
+local current = redis.call('PTTL', KEYS[1])
+if not existed or (current >= 0 and current < ttl) then
+	return redis.call('PEXPIRE', KEYS[1], ttl)
+end
+return 0
+`)
+
+// Set stores data in the cache with the given key and expiration time. Response
+// metadata attached with ports.WithCacheEntryMetadata is stored in the value header.
+// Tags attached with ports.WithCacheTags are stored there too, and add the key to a
+// set per tag, which expires with the longest-lived key in it; members outliving
+// their key are harmless, pruned by KeysByTag and dropped when the tag is purged.
 func (c *RedisCache) Set(ctx context.Context, key string, value io.ReadCloser, expiration time.Duration) error {
-	// Read the data from the ReadCloser
-	data, err := io.ReadAll(value)
+	// Read the data from the ReadCloser, reusing the fetcher's buffer when possible
+	data, err := ports.ReadAll(value)
 	if err != nil {
 		return err
 	}
 
-	// Reset the ReadCloser for further use
-	value = io.NopCloser(bytes.NewReader(data))
+	tags := ports.CacheTagsFromContext(ctx)
+	metadata := valueMetadata{Tags: tags}
+	if entry, ok := ports.CacheEntryMetadataFromContext(ctx); ok {
+		metadata.CacheEntryMetadata = &entry
+	}
+	stored, err := encodeValue(c.codec, data, metadata)
+	if err != nil {
+		return err
+	}
 
 	// Store the data in Redis
-	return c.client.Set(ctx, key, data, expiration).Err()
+	if len(tags) > 0 {
+		_, err = c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, stored, expiration)
+			for _, tag := range tags {
+				tagScript.Eval(ctx, pipe, []string{tagSetPrefix + tag}, key, expiration.Milliseconds())
+			}
+			return nil
+		})
+	} else {
+		err = c.client.Set(ctx, key, stored, expiration).Err()
+	}
+	if err != nil {
+		return err
+	}
+
+	c.uncompressedBytes.Add(int64(len(data)))
+	c.storedBytes.Add(int64(len(stored)))
+	return nil
 }
 
 // Delete removes data from the cache for the given key
@@ -63,5 +145,87 @@ func (c *RedisCache) Delete(ctx context.Context, key string) error {
 	return c.client.Del(ctx, key).Err()
 }
 
-// Ensure RedisCache implements CacheService
-var _ ports.CacheService = (*RedisCache)(nil)
+// KeysByTag implements ports.TaggedCache. Members whose key has expired are
+// removed from the tag set.
+func (c *RedisCache) KeysByTag(ctx context.Context, tag string) ([]string, error) {
+	members, err := c.client.SMembers(ctx, tagSetPrefix+tag).Result()
+	if err != nil || len(members) == 0 {
+		return members, err
+	}
+
+	// One EXISTS per key, as the keys may live on different cluster nodes
+	exists := make([]*redis.IntCmd, len(members))
+	_, err = c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range members {
+			exists[i] = pipe.Exists(ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(members))
+	var stale []interface{}
+	for i, key := range members {
+		if exists[i].Val() > 0 {
+			keys = append(keys, key)
+		} else {
+			stale = append(stale, key)
+		}
+	}
+	if len(stale) > 0 {
+		if err := c.client.SRem(ctx, tagSetPrefix+tag, stale...).Err(); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// DeleteByTag implements ports.TaggedCache. Keys are deleted one by one so the
+// purge also works when they live on different cluster nodes.
+func (c *RedisCache) DeleteByTag(ctx context.Context, tag string) (int, error) {
+	keys, err := c.KeysByTag(ctx, tag)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, key := range keys {
+		n, err := c.client.Del(ctx, key).Result()
+		if err != nil {
+			return deleted, err
+		}
+		deleted += int(n)
+	}
+
+	if err := c.client.Del(ctx, tagSetPrefix+tag).Err(); err != nil {
+		return deleted, err
+	}
+	return deleted, nil
+}
+
+// Stats implements ports.CacheStatsProvider. Compression figures cover the values
+// written since this process started.
+func (c *RedisCache) Stats(ctx context.Context) (ports.CacheStats, error) {
+	stats := ports.CacheStats{
+		Backend:           "redis",
+		Hits:              c.hits.Load(),
+		Misses:            c.misses.Load(),
+		Codec:             string(c.codec),
+		UncompressedBytes: c.uncompressedBytes.Load(),
+		StoredBytes:       c.storedBytes.Load(),
+	}
+	if stats.StoredBytes > 0 {
+		stats.CompressionRatio = float64(stats.UncompressedBytes) / float64(stats.StoredBytes)
+		stats.BytesSaved = stats.UncompressedBytes - stats.StoredBytes
+	}
+	return stats, nil
+}
+
+// Ensure RedisCache implements CacheService, CacheStatsProvider and TaggedCache
+var (
+	_ ports.CacheService       = (*RedisCache)(nil)
+	_ ports.CacheStatsProvider = (*RedisCache)(nil)
+	_ ports.TaggedCache        = (*RedisCache)(nil)
+)
@@ -0,0 +1,218 @@
+package cache
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisMode selects how the Redis deployment is reached
+type RedisMode string
+
+const (
+	RedisStandalone RedisMode = "standalone"
+	RedisSentinel   RedisMode = "sentinel"
+	RedisCluster    RedisMode = "cluster"
+)
+
+// defaultRedisAddr is used when neither URL nor Addrs is set
+const defaultRedisAddr = "localhost:6379"
+
+// RedisConfig describes the Redis deployment backing a RedisCache. URL, when set,
+// is parsed first and the other fields override what it sets.
+type RedisConfig struct {
+	// URL is a redis:// or rediss:// URL. In sentinel mode its host and credentials
+	// are the sentinel's and master_name is a query parameter; in sentinel and
+	// cluster modes further nodes are given with addr query parameters.
+	URL  string
+	Mode RedisMode
+	// Addrs lists the server address in standalone mode, the sentinels in sentinel
+	// mode and the seed nodes in cluster mode
+	Addrs      []string
+	MasterName string
+
+	Username string
+	Password string
+	// SentinelUsername and SentinelPassword authenticate against the sentinels
+	// when they differ from the master's credentials
+	SentinelUsername string
+	SentinelPassword string
+	// DB is ignored by Redis Cluster, which only has database 0
+	DB int
+
+	TLS                   bool
+	TLSCAFile             string
+	TLSServerName         string
+	TLSInsecureSkipVerify bool
+
+	// Pool settings; zero keeps the go-redis defaults
+	PoolSize        int
+	MinIdleConns    int
+	MaxIdleConns    int
+	ConnMaxIdleTime time.Duration
+	PoolTimeout     time.Duration
+	DialTimeout     time.Duration
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+
+	Codec Codec
+}
+
+// universalOptions turns the configuration into options for redis.NewUniversalClient
+func (c RedisConfig) universalOptions() (*redis.UniversalOptions, error) {
+	mode := c.Mode
+	if mode == "" {
+		mode = RedisStandalone
+	}
+
+	options := &redis.UniversalOptions{}
+	if c.URL != "" {
+		if err := applyRedisURL(options, mode, c.URL); err != nil {
+			return nil, fmt.Errorf("invalid Redis URL: %w", err)
+		}
+	}
+
+	if len(c.Addrs) > 0 {
+		options.Addrs = c.Addrs
+	}
+	if c.MasterName != "" {
+		options.MasterName = c.MasterName
+	}
+	if c.Username != "" {
+		options.Username = c.Username
+	}
+	if c.Password != "" {
+		options.Password = c.Password
+	}
+	if c.SentinelUsername != "" {
+		options.SentinelUsername = c.SentinelUsername
+	}
+	if c.SentinelPassword != "" {
+		options.SentinelPassword = c.SentinelPassword
+	}
+	if c.DB != 0 {
+		options.DB = c.DB
+	}
+	setIfPositive(&options.PoolSize, c.PoolSize)
+	setIfPositive(&options.MinIdleConns, c.MinIdleConns)
+	setIfPositive(&options.MaxIdleConns, c.MaxIdleConns)
+	setIfPositive(&options.ConnMaxIdleTime, c.ConnMaxIdleTime)
+	setIfPositive(&options.PoolTimeout, c.PoolTimeout)
+	setIfPositive(&options.DialTimeout, c.DialTimeout)
+	setIfPositive(&options.ReadTimeout, c.ReadTimeout)
+	setIfPositive(&options.WriteTimeout, c.WriteTimeout)
+
+	if len(options.Addrs) == 0 {
+		options.Addrs = []string{defaultRedisAddr}
+	}
+
+	// NewUniversalClient picks the client from the options, so make sure they say what the mode means
+	switch mode {
+	case RedisStandalone:
+		if len(options.Addrs) > 1 {
+			return nil, errors.New("standalone Redis takes a single address; use cluster or sentinel mode for several")
+		}
+		options.MasterName = ""
+	case RedisSentinel:
+		if options.MasterName == "" {
+			return nil, errors.New("sentinel mode requires a master name")
+		}
+	case RedisCluster:
+		if options.MasterName != "" {
+			return nil, errors.New("cluster mode does not take a master name")
+		}
+		options.IsClusterMode = true
+		options.DB = 0
+	default:
+		return nil, fmt.Errorf("unknown Redis mode %q", mode)
+	}
+
+	tlsConfig, err := c.tlsConfig(options.TLSConfig)
+	if err != nil {
+		return nil, err
+	}
+	options.TLSConfig = tlsConfig
+
+	return options, nil
+}
+
+// applyRedisURL copies the settings of a Redis URL into options, using the parser
+// go-redis provides for the mode
+func applyRedisURL(options *redis.UniversalOptions, mode RedisMode, rawURL string) error {
+	switch mode {
+	case RedisSentinel:
+		parsed, err := redis.ParseFailoverURL(rawURL)
+		if err != nil {
+			return err
+		}
+		options.Addrs = parsed.SentinelAddrs
+		options.MasterName = parsed.MasterName
+		options.SentinelUsername = parsed.SentinelUsername
+		options.SentinelPassword = parsed.SentinelPassword
+		options.Username = parsed.Username
+		options.Password = parsed.Password
+		options.DB = parsed.DB
+		options.TLSConfig = parsed.TLSConfig
+	case RedisCluster:
+		parsed, err := redis.ParseClusterURL(rawURL)
+		if err != nil {
+			return err
+		}
+		options.Addrs = parsed.Addrs
+		options.Username = parsed.Username
+		options.Password = parsed.Password
+		options.TLSConfig = parsed.TLSConfig
+	default:
+		parsed, err := redis.ParseURL(rawURL)
+		if err != nil {
+			return err
+		}
+		options.Addrs = []string{parsed.Addr}
+		options.Username = parsed.Username
+		options.Password = parsed.Password
+		options.DB = parsed.DB
+		options.TLSConfig = parsed.TLSConfig
+	}
+	return nil
+}
+
+// tlsConfig builds the TLS settings, starting from those of a rediss:// URL
+func (c RedisConfig) tlsConfig(base *tls.Config) (*tls.Config, error) {
+	if base == nil && !c.TLS {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if base != nil {
+		config = base.Clone()
+	}
+	if c.TLSServerName != "" {
+		config.ServerName = c.TLSServerName
+	}
+	if c.TLSInsecureSkipVerify {
+		config.InsecureSkipVerify = true
+	}
+	if c.TLSCAFile != "" {
+		pem, err := os.ReadFile(c.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Redis CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in Redis CA file %s", c.TLSCAFile)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+func setIfPositive[T int | time.Duration](target *T, value T) {
+	if value > 0 {
+		*target = value
+	}
+}
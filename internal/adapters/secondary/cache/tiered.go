@@ -0,0 +1,302 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"web-crawler-go/internal/core/ports"
+)
+
+// WritePolicy controls how a tier is written when a value is stored
+type WritePolicy string
+
+const (
+	// WriteThrough stores the value in the tier before Set returns
+	WriteThrough WritePolicy = "write_through"
+	// WriteAsync stores the value in the tier in the background
+	WriteAsync WritePolicy = "write_async"
+	// WriteNone never stores new values; the tier is only filled by promotion
+	WriteNone WritePolicy = "none"
+)
+
+// asyncWriteTimeout bounds background writes and promotions
+const asyncWriteTimeout = 10 * time.Second
+
+// CacheTier configures one layer of a TieredCache
+type CacheTier struct {
+	Name        string
+	Cache       ports.CacheService
+	WritePolicy WritePolicy
+	// PromotionTTL caps how long a hit in a lower tier is kept when it is copied into
+	// this one; the copy never outlives the entry it came from. Zero means no cap.
+	PromotionTTL time.Duration
+}
+
+// tier is a CacheTier with its counters
+type tier struct {
+	CacheTier
+	hits       atomic.Int64
+	misses     atomic.Int64
+	promotions atomic.Int64
+	errors     atomic.Int64
+}
+
+// TieredCache implements the CacheService interface over several caches, fastest
+// first. Reads go down the tiers and copy a hit into every faster tier; writes go
+// to each tier according to its write policy.
+type TieredCache struct {
+	tiers  []*tier
+	logger ports.Logger
+}
+
+// NewTieredCache creates a cache from tiers ordered from fastest to slowest
+func NewTieredCache(logger ports.Logger, tiers ...CacheTier) (*TieredCache, error) {
+	if len(tiers) == 0 {
+		return nil, errors.New("tiered cache needs at least one tier")
+	}
+
+	c := &TieredCache{logger: logger}
+	for _, t := range tiers {
+		switch t.WritePolicy {
+		case "":
+			t.WritePolicy = WriteThrough
+		case WriteThrough, WriteAsync, WriteNone:
+		default:
+			return nil, fmt.Errorf("unknown write policy %q for tier %s", t.WritePolicy, t.Name)
+		}
+		c.tiers = append(c.tiers, &tier{CacheTier: t})
+	}
+
+	return c, nil
+}
+
+// Get retrieves data from the first tier holding the key and promotes it to the faster ones
+func (c *TieredCache) Get(ctx context.Context, key string) (io.ReadCloser, bool, error) {
+	for i, t := range c.tiers {
+		value, found, err := t.Cache.Get(ctx, key)
+		if err != nil {
+			// A failing tier is skipped rather than failing the whole lookup
+			t.errors.Add(1)
+			c.logger.Warn("cache tier get failed", "tier", t.Name, "error", err)
+			continue
+		}
+		if !found {
+			t.misses.Add(1)
+			continue
+		}
+		t.hits.Add(1)
+
+		if i == 0 {
+			return value, true, nil
+		}
+
+		data, err := ports.ReadAll(value)
+		value.Close()
+		if err != nil {
+			t.errors.Add(1)
+			return nil, false, err
+		}
+
+		var metadata *ports.CacheEntryMetadata
+		if provider, ok := value.(ports.CacheEntryMetadataProvider); ok {
+			m := provider.CacheEntryMetadata()
+			metadata = &m
+		}
+		// Without storage details the entry is promoted for PromotionTTL, untagged
+		var storage ports.CacheEntryStorage
+		if provider, ok := value.(ports.CacheEntryStorageProvider); ok {
+			storage = provider.CacheEntryStorage()
+		}
+		c.promote(key, data, metadata, storage, c.tiers[:i])
+
+		return newEntryBody(data, metadata, storage), true, nil
+	}
+
+	return nil, false, nil
+}
+
+// promotionTTL is how long a copy promoted into t may live: no longer than the
+// source entry has left, capped by the tier's PromotionTTL. It returns false when
+// the source entry has already expired.
+func (t *tier) promotionTTL(storage ports.CacheEntryStorage, now time.Time) (time.Duration, bool) {
+	if storage.ExpiresAt.IsZero() {
+		return t.PromotionTTL, true
+	}
+	remaining := storage.ExpiresAt.Sub(now)
+	if remaining <= 0 {
+		return 0, false
+	}
+	if t.PromotionTTL > 0 && t.PromotionTTL < remaining {
+		return t.PromotionTTL, true
+	}
+	return remaining, true
+}
+
+// promote copies a value found in a lower tier into the given faster tiers in the
+// background, with the source's response metadata and tags so the copies are purged
+// with it
+func (c *TieredCache) promote(key string, data []byte, metadata *ports.CacheEntryMetadata, storage ports.CacheEntryStorage, tiers []*tier) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), asyncWriteTimeout)
+		defer cancel()
+		if metadata != nil {
+			ctx = ports.WithCacheEntryMetadata(ctx, *metadata)
+		}
+		if len(storage.Tags) > 0 {
+			ctx = ports.WithCacheTags(ctx, storage.Tags...)
+		}
+
+		now := time.Now()
+		for _, t := range tiers {
+			ttl, ok := t.promotionTTL(storage, now)
+			if !ok {
+				return
+			}
+			if err := t.Cache.Set(ctx, key, newBufferedBody(data), ttl); err != nil {
+				t.errors.Add(1)
+				c.logger.Warn("cache tier promotion failed", "tier", t.Name, "error", err)
+				continue
+			}
+			t.promotions.Add(1)
+		}
+	}()
+}
+
+// Set stores data in every tier according to its write policy
+func (c *TieredCache) Set(ctx context.Context, key string, value io.ReadCloser, expiration time.Duration) error {
+	// Buffer once; each tier gets its own reader over the same bytes
+	data, err := ports.ReadAll(value)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, t := range c.tiers {
+		switch t.WritePolicy {
+		case WriteThrough:
+			if err := t.Cache.Set(ctx, key, newBufferedBody(data), expiration); err != nil {
+				t.errors.Add(1)
+				errs = append(errs, fmt.Errorf("tier %s: %w", t.Name, err))
+			}
+		case WriteAsync:
+			asyncCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), asyncWriteTimeout)
+			go func(t *tier) {
+				defer cancel()
+				if err := t.Cache.Set(asyncCtx, key, newBufferedBody(data), expiration); err != nil {
+					t.errors.Add(1)
+					c.logger.Warn("cache tier async write failed", "tier", t.Name, "error", err)
+				}
+			}(t)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Delete removes data from every tier
+func (c *TieredCache) Delete(ctx context.Context, key string) error {
+	var errs []error
+	for _, t := range c.tiers {
+		if err := t.Cache.Delete(ctx, key); err != nil {
+			t.errors.Add(1)
+			errs = append(errs, fmt.Errorf("tier %s: %w", t.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// KeysByTag implements ports.TaggedCache, merging the keys every tagged tier knows.
+// Promoted copies carry the tags of their source when its tier returns them.
+func (c *TieredCache) KeysByTag(ctx context.Context, tag string) ([]string, error) {
+	seen := make(map[string]struct{})
+	var keys []string
+	var errs []error
+	for _, t := range c.tiers {
+		tagged, ok := t.Cache.(ports.TaggedCache)
+		if !ok {
+			continue
+		}
+		tierKeys, err := tagged.KeysByTag(ctx, tag)
+		if err != nil {
+			t.errors.Add(1)
+			errs = append(errs, fmt.Errorf("tier %s: %w", t.Name, err))
+			continue
+		}
+		for _, key := range tierKeys {
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys, errors.Join(errs...)
+}
+
+// DeleteByTag implements ports.TaggedCache. The keys found in any tier are deleted
+// from all of them, which also catches copies promoted from a tier that does not
+// return tags.
+func (c *TieredCache) DeleteByTag(ctx context.Context, tag string) (int, error) {
+	keys, err := c.KeysByTag(ctx, tag)
+	if err != nil && len(keys) == 0 {
+		return 0, err
+	}
+
+	errs := []error{err}
+	for _, key := range keys {
+		if deleteErr := c.Delete(ctx, key); deleteErr != nil {
+			errs = append(errs, deleteErr)
+		}
+	}
+	for _, t := range c.tiers {
+		// Let the tier drop its own index for the tag
+		if tagged, ok := t.Cache.(ports.TaggedCache); ok {
+			if _, tierErr := tagged.DeleteByTag(ctx, tag); tierErr != nil {
+				t.errors.Add(1)
+				errs = append(errs, fmt.Errorf("tier %s: %w", t.Name, tierErr))
+			}
+		}
+	}
+	return len(keys), errors.Join(errs...)
+}
+
+// Stats implements ports.CacheStatsProvider. The totals only cover hits and misses
+// since the same entry may be stored in several tiers; the rest is reported per tier.
+func (c *TieredCache) Stats(ctx context.Context) (ports.CacheStats, error) {
+	stats := ports.CacheStats{Backend: "tiered"}
+
+	for _, t := range c.tiers {
+		tierStats := ports.CacheStats{Backend: t.Name}
+		if provider, ok := t.Cache.(ports.CacheStatsProvider); ok {
+			backendStats, err := provider.Stats(ctx)
+			if err != nil {
+				c.logger.Warn("failed to get cache tier stats", "tier", t.Name, "error", err)
+			} else {
+				tierStats = backendStats
+			}
+		}
+		// Hits and misses are counted here so they reflect lookups through the tiers
+		tierStats.Tier = t.Name
+		tierStats.Hits = t.hits.Load()
+		tierStats.Misses = t.misses.Load()
+		tierStats.Promotions = t.promotions.Load()
+		tierStats.Errors = t.errors.Load()
+
+		stats.Hits += tierStats.Hits
+		stats.Tiers = append(stats.Tiers, tierStats)
+	}
+	// A lookup is a miss overall only when the last tier missed as well
+	stats.Misses = c.tiers[len(c.tiers)-1].misses.Load()
+
+	return stats, nil
+}
+
+// Ensure TieredCache implements CacheService, CacheStatsProvider and TaggedCache
+var (
+	_ ports.CacheService       = (*TieredCache)(nil)
+	_ ports.CacheStatsProvider = (*TieredCache)(nil)
+	_ ports.TaggedCache        = (*TieredCache)(nil)
+)
@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"web-crawler-go/internal/core/ports"
+	"web-crawler-go/internal/core/services/loggerservice"
+)
+
+const domainTag = "domain:shop.example.tw"
+
+// newTestTieredCache layers a memory cache over a disk cache
+func newTestTieredCache(t *testing.T, promotionTTL time.Duration) (*TieredCache, *MemoryCache, *FileSystemCache) {
+	t.Helper()
+	memory := NewMemoryCache(1<<20, 0)
+	t.Cleanup(func() { memory.Close() })
+	disk := newTestFileSystemCache(t, 0)
+
+	tiered, err := NewTieredCache(loggerservice.NewLoggerService(),
+		CacheTier{Name: "memory", Cache: memory, PromotionTTL: promotionTTL},
+		CacheTier{Name: "disk", Cache: disk},
+	)
+	if err != nil {
+		t.Fatalf("NewTieredCache: %v", err)
+	}
+	return tiered, memory, disk
+}
+
+// waitForPromotion waits until the background promotion has stored key in the
+// memory tier and returns how the copy is stored
+func waitForPromotion(t *testing.T, memory *MemoryCache, key string) ports.CacheEntryStorage {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		memory.mu.Lock()
+		element, ok := memory.entries[key]
+		memory.mu.Unlock()
+		if ok {
+			entry := element.Value.(*memoryEntry)
+			return ports.CacheEntryStorage{ExpiresAt: entry.expiresAt, Tags: entry.tags}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("%s was not promoted", key)
+	return ports.CacheEntryStorage{}
+}
+
+func TestTieredCachePromotionKeepsSourceExpiryAndTags(t *testing.T) {
+	tests := []struct {
+		name         string
+		promotionTTL time.Duration
+		sourceTTL    time.Duration
+		// wantTTL is zero for a copy that never expires
+		wantTTL time.Duration
+	}{
+		{name: "source expires first", promotionTTL: time.Hour, sourceTTL: 10 * time.Minute, wantTTL: 10 * time.Minute},
+		{name: "capped by the promotion TTL", promotionTTL: time.Hour, sourceTTL: 3 * time.Hour, wantTTL: time.Hour},
+		{name: "no cap", promotionTTL: 0, sourceTTL: 10 * time.Minute, wantTTL: 10 * time.Minute},
+		{name: "source never expires", promotionTTL: time.Hour, sourceTTL: 0, wantTTL: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tiered, memory, disk := newTestTieredCache(t, tt.promotionTTL)
+			metadata := ports.CacheEntryMetadata{URL: "https://shop.example.tw/", StatusCode: 200}
+			ctx := ports.WithCacheTags(ports.WithCacheEntryMetadata(context.Background(), metadata), domainTag)
+			if err := disk.Set(ctx, fetcherKey, value("<html></html>"), tt.sourceTTL); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+
+			before := time.Now()
+			body, found, err := tiered.Get(context.Background(), fetcherKey)
+			if err != nil || !found {
+				t.Fatalf("Get = %v, %v, want a hit in the disk tier", found, err)
+			}
+			if provider, ok := body.(ports.CacheEntryMetadataProvider); !ok || provider.CacheEntryMetadata().URL != metadata.URL {
+				t.Errorf("promoted body %T lost its response metadata", body)
+			}
+
+			storage := waitForPromotion(t, memory, fetcherKey)
+			if len(storage.Tags) != 1 || storage.Tags[0] != domainTag {
+				t.Errorf("promoted tags = %v, want [%s]", storage.Tags, domainTag)
+			}
+			if tt.wantTTL == 0 {
+				if !storage.ExpiresAt.IsZero() {
+					t.Errorf("promoted copy expires at %v, want never", storage.ExpiresAt)
+				}
+				return
+			}
+			ttl := storage.ExpiresAt.Sub(before)
+			if ttl > tt.wantTTL+time.Second || ttl < tt.wantTTL-5*time.Second {
+				t.Errorf("promoted copy lives %v, want about %v", ttl, tt.wantTTL)
+			}
+		})
+	}
+}
+
+func TestTieredCachePromotedCopyExpires(t *testing.T) {
+	tiered, memory, disk := newTestTieredCache(t, time.Hour)
+	disk.Set(context.Background(), fetcherKey, value("<html></html>"), 10*time.Minute)
+	if _, found, _ := tiered.Get(context.Background(), fetcherKey); !found {
+		t.Fatalf("Get missed the disk tier")
+	}
+	waitForPromotion(t, memory, fetcherKey)
+
+	// Once the source would have expired, so has the copy
+	later := time.Now().Add(11 * time.Minute)
+	memory.now = func() time.Time { return later }
+	disk.now = func() time.Time { return later }
+	if _, found, _ := tiered.Get(context.Background(), fetcherKey); found {
+		t.Errorf("promoted copy outlived its source")
+	}
+}
+
+func TestTieredCachePurgeRemovesPromotedCopies(t *testing.T) {
+	tiered, memory, disk := newTestTieredCache(t, time.Hour)
+	ctx := ports.WithCacheTags(context.Background(), domainTag)
+	disk.Set(ctx, fetcherKey, value("<html></html>"), time.Hour)
+	if _, found, _ := tiered.Get(context.Background(), fetcherKey); !found {
+		t.Fatalf("Get missed the disk tier")
+	}
+	waitForPromotion(t, memory, fetcherKey)
+
+	// The source is gone, so only the memory tier's own tag index knows the copy
+	disk.Delete(context.Background(), fetcherKey)
+
+	deleted, err := tiered.DeleteByTag(context.Background(), domainTag)
+	if err != nil || deleted != 1 {
+		t.Errorf("DeleteByTag = %d, %v, want 1", deleted, err)
+	}
+	if _, found, _ := tiered.Get(context.Background(), fetcherKey); found {
+		t.Errorf("promoted copy survived the purge of its domain")
+	}
+}
@@ -0,0 +1,128 @@
+package fetcher
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+	"web-crawler-go/internal/core/ports"
+)
+
+// domainTagPrefix prefixes the cache tags grouping entries by host
+const domainTagPrefix = "domain:"
+
+// domainTags returns the cache tags for a fetched URL: its host and every parent
+// domain down to the registrable domain, so purging "shop.com.tw" also removes
+// entries fetched from "www.shop.com.tw" and "img.cdn.shop.com.tw".
+func domainTags(rawURL string) []string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return nil
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	tags := []string{domainTagPrefix + host}
+	if net.ParseIP(host) != nil {
+		return tags
+	}
+	registrable, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil || !strings.HasSuffix(host, "."+registrable) {
+		return tags
+	}
+	for parent := host; parent != registrable; {
+		_, parent, _ = strings.Cut(parent, ".")
+		tags = append(tags, domainTagPrefix+parent)
+	}
+	return tags
+}
+
+// LookupURL implements ports.CacheAdmin. Reading the entry counts as a cache hit.
+func (f *HTTPFetcher) LookupURL(ctx context.Context, url string) (*ports.CachedURL, bool, error) {
+	if f.cache == nil {
+		return nil, false, ports.ErrCacheUnsupported
+	}
+
+	cacheKey := generateCacheKey(url)
+	entry := &ports.CachedURL{URL: url, Key: cacheKey}
+
+	cached, found, err := f.cache.Get(ctx, cacheKey)
+	if err != nil {
+		return nil, false, err
+	}
+	if !found {
+		entry.Key = negativeCacheKey(cacheKey)
+		cached, found, err = f.cache.Get(ctx, entry.Key)
+		if err != nil || !found {
+			return nil, false, err
+		}
+		entry.NotFound = true
+	}
+	defer cached.Close()
+
+	data, err := ports.ReadAll(cached)
+	if err != nil {
+		return nil, false, err
+	}
+	entry.SizeBytes = int64(len(data))
+	if entry.NotFound {
+		entry.StatusCode, _ = strconv.Atoi(string(data))
+	}
+
+	if provider, ok := cached.(ports.CacheEntryMetadataProvider); ok {
+		metadata := provider.CacheEntryMetadata()
+		entry.StatusCode = metadata.StatusCode
+		entry.ContentType = metadata.Header.Get("Content-Type")
+		entry.FetchedAt = metadata.FetchedAt
+		entry.Header = metadata.Header
+	} else if !entry.NotFound {
+		entry.StatusCode = http.StatusOK
+	}
+
+	return entry, true, nil
+}
+
+// InvalidateURL implements ports.CacheAdmin, removing the body and any remembered 404
+func (f *HTTPFetcher) InvalidateURL(ctx context.Context, url string) error {
+	if f.cache == nil {
+		return ports.ErrCacheUnsupported
+	}
+
+	cacheKey := generateCacheKey(url)
+	if err := f.cache.Delete(ctx, cacheKey); err != nil {
+		return err
+	}
+	f.logger.Info("cache entry invalidated", "url", url)
+	return f.cache.Delete(ctx, negativeCacheKey(cacheKey))
+}
+
+// InvalidateDomain implements ports.CacheAdmin
+func (f *HTTPFetcher) InvalidateDomain(ctx context.Context, domain string) (int, error) {
+	tagged, ok := f.cache.(ports.TaggedCache)
+	if !ok {
+		return 0, ports.ErrCacheUnsupported
+	}
+
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	deleted, err := tagged.DeleteByTag(ctx, domainTagPrefix+domain)
+	if err != nil {
+		return deleted, err
+	}
+	f.logger.Info("cache domain purged", "domain", domain, "deleted", deleted)
+	return deleted, nil
+}
+
+// CacheStats implements ports.CacheAdmin
+func (f *HTTPFetcher) CacheStats(ctx context.Context) (ports.CacheStats, error) {
+	provider, ok := f.cache.(ports.CacheStatsProvider)
+	if !ok {
+		return ports.CacheStats{}, ports.ErrCacheUnsupported
+	}
+	return provider.Stats(ctx)
+}
+
+// Ensure HTTPFetcher implements CacheAdmin
+var _ ports.CacheAdmin = (*HTTPFetcher)(nil)
@@ -0,0 +1,92 @@
+package fetcher
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"regexp"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// charsetSniffLength is how far into the body we look for a declared charset
+const charsetSniffLength = 4096
+
+var (
+	// Matches both <meta charset="big5"> and <meta http-equiv="Content-Type" content="text/html; charset=big5">
+	metaCharsetPattern = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_:.\-]+)`)
+	// Matches the encoding in an XML declaration such as <?xml version="1.0" encoding="Shift_JIS"?>
+	xmlEncodingPattern = regexp.MustCompile(`(?i)^\s*<\?xml[^>]+encoding\s*=\s*["']([a-z0-9_:.\-]+)["']`)
+)
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+)
+
+// detectCharset works out the encoding of body, in order of precedence from the
+// byte order mark, the Content-Type header and a <meta> or XML declaration.
+// Bodies that declare nothing are assumed to be UTF-8.
+func detectCharset(body []byte, contentType string) (encoding.Encoding, string) {
+	switch {
+	case bytes.HasPrefix(body, utf8BOM):
+		return unicode.UTF8BOM, "utf-8"
+	case bytes.HasPrefix(body, utf16BEBOM):
+		return unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM), "utf-16be"
+	case bytes.HasPrefix(body, utf16LEBOM):
+		return unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM), "utf-16le"
+	}
+
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		if enc, name, ok := lookupCharset(params["charset"]); ok {
+			return enc, name
+		}
+	}
+
+	head := body
+	if len(head) > charsetSniffLength {
+		head = head[:charsetSniffLength]
+	}
+	for _, pattern := range []*regexp.Regexp{xmlEncodingPattern, metaCharsetPattern} {
+		if match := pattern.FindSubmatch(head); match != nil {
+			if enc, name, ok := lookupCharset(string(match[1])); ok {
+				return enc, name
+			}
+		}
+	}
+
+	return unicode.UTF8, "utf-8"
+}
+
+// lookupCharset resolves a charset label using the WHATWG encoding names
+func lookupCharset(label string) (encoding.Encoding, string, bool) {
+	if label == "" {
+		return nil, "", false
+	}
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return nil, "", false
+	}
+	name, err := htmlindex.Name(enc)
+	if err != nil {
+		name = label
+	}
+	return enc, name, true
+}
+
+// transcodeToUTF8 converts body to UTF-8, returning it with the detected original charset
+func transcodeToUTF8(body []byte, contentType string) ([]byte, string, error) {
+	enc, name := detectCharset(body, contentType)
+	if enc == unicode.UTF8 {
+		return body, name, nil
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return nil, name, fmt.Errorf("failed to transcode %s body to UTF-8: %w", name, err)
+	}
+	return decoded, name, nil
+}
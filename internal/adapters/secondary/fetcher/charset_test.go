@@ -0,0 +1,131 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"web-crawler-go/internal/core/ports"
+	"web-crawler-go/internal/core/services/loggerservice"
+)
+
+// charsetPage is a page served in a legacy encoding
+type charsetPage struct {
+	name        string
+	encoding    encoding.Encoding
+	contentType string
+	// html is the page in UTF-8, before it is encoded
+	html        string
+	text        string
+	wantCharset string
+}
+
+var charsetPages = []charsetPage{
+	{
+		name:        "big5 from the header",
+		encoding:    traditionalchinese.Big5,
+		contentType: "text/html; charset=big5",
+		html:        "<html><body><h1>烏龍茶禮盒</h1></body></html>",
+		text:        "烏龍茶禮盒",
+		wantCharset: "big5",
+	},
+	{
+		name:        "shift_jis from a meta tag",
+		encoding:    japanese.ShiftJIS,
+		contentType: "text/html",
+		html:        `<html><head><meta charset="Shift_JIS"></head><body><h1>抹茶の茶碗</h1></body></html>`,
+		text:        "抹茶の茶碗",
+		wantCharset: "shift_jis",
+	},
+}
+
+func (p charsetPage) encoded(t *testing.T) []byte {
+	t.Helper()
+	body, err := p.encoding.NewEncoder().Bytes([]byte(p.html))
+	if err != nil {
+		t.Fatalf("failed to encode %s page: %v", p.name, err)
+	}
+	return body
+}
+
+// checkDecoded reads a fetched body and checks its text and recorded charset
+func checkDecoded(t *testing.T, body io.ReadCloser, page charsetPage) {
+	t.Helper()
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+	if !strings.Contains(string(data), page.text) {
+		t.Errorf("body = %q, want it to contain %q", data, page.text)
+	}
+	provider, ok := body.(ports.FetchMetadataProvider)
+	if !ok {
+		t.Fatalf("body %T carries no fetch metadata", body)
+	}
+	if got := provider.FetchMetadata().Charset; got != page.wantCharset {
+		t.Errorf("charset = %q, want %q", got, page.wantCharset)
+	}
+}
+
+func TestFetchTranscodesLegacyCharsets(t *testing.T) {
+	for _, page := range charsetPages {
+		t.Run(page.name, func(t *testing.T) {
+			body := page.encoded(t)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", page.contentType)
+				w.Write(body)
+			}))
+			defer server.Close()
+
+			f, err := NewHTTPFetcher(nil, nil, DefaultConfig(), loggerservice.NewLoggerService())
+			if err != nil {
+				t.Fatalf("NewHTTPFetcher: %v", err)
+			}
+			fetched, err := f.Fetch(context.Background(), server.URL)
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			checkDecoded(t, fetched, page)
+		})
+	}
+}
+
+func TestReplayTranscodesLegacyCharsets(t *testing.T) {
+	dir := t.TempDir()
+	index := map[string]fixture{}
+	for _, page := range charsetPages {
+		file := filepath.Join(dir, page.wantCharset+".html")
+		if err := os.WriteFile(file, page.encoded(t), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		index["https://shop.example.tw/"+page.wantCharset] = fixture{File: filepath.Base(file), ContentType: page.contentType}
+	}
+	data, _ := json.Marshal(index)
+	if err := os.WriteFile(filepath.Join(dir, fixtureIndexFile), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := NewReplayFetcher(dir, "", loggerservice.NewLoggerService())
+	if err != nil {
+		t.Fatalf("NewReplayFetcher: %v", err)
+	}
+	for _, page := range charsetPages {
+		t.Run(page.name, func(t *testing.T) {
+			fetched, err := f.Fetch(context.Background(), "https://shop.example.tw/"+page.wantCharset)
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			checkDecoded(t, fetched, page)
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package fetcher
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultUserAgent = "Mozilla/5.0 (compatible; web-crawler-go/1.0)"
+
+// Duration wraps time.Duration so it can be written as "30s" in JSON config files
+type Duration time.Duration
+
+// UnmarshalJSON accepts either a duration string ("1m30s") or a number of nanoseconds
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", v, err)
+		}
+		*d = Duration(parsed)
+	case float64:
+		*d = Duration(time.Duration(v))
+	default:
+		return fmt.Errorf("invalid duration %s", string(data))
+	}
+	return nil
+}
+
+// MarshalJSON writes the duration in its string form
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Config controls how the HTTP fetcher builds its clients.
+// Zero values in a host override fall back to the top-level settings.
+type Config struct {
+	// UserAgent is sent with every request
+	UserAgent string `json:"user_agent"`
+	// Headers are added to every request
+	Headers map[string]string `json:"headers"`
+
+	// Timeout bounds a whole request, including redirects and reading the body
+	Timeout Duration `json:"timeout"`
+	// DialTimeout bounds establishing the TCP connection
+	DialTimeout Duration `json:"dial_timeout"`
+	// TLSHandshakeTimeout bounds the TLS handshake
+	TLSHandshakeTimeout Duration `json:"tls_handshake_timeout"`
+	// ResponseHeaderTimeout bounds waiting for response headers once the request is written
+	ResponseHeaderTimeout Duration `json:"response_header_timeout"`
+	// IdleConnTimeout is how long an idle keep-alive connection stays in the pool
+	IdleConnTimeout Duration `json:"idle_conn_timeout"`
+
+	// MaxBodySize caps response bodies in bytes; zero uses the 20 MiB default
+	MaxBodySize int64 `json:"max_body_size"`
+	// MaxBodySizeByType caps bodies of specific media types, e.g. "application/json" or "image/*"
+	MaxBodySizeByType map[string]int64 `json:"max_body_size_by_type"`
+
+	MaxIdleConns        int `json:"max_idle_conns"`
+	MaxIdleConnsPerHost int `json:"max_idle_conns_per_host"`
+	MaxConnsPerHost     int `json:"max_conns_per_host"`
+
+	// ProxyURL is an http://, https:// or socks5:// proxy, optionally with user:password.
+	// When empty the standard HTTP_PROXY/HTTPS_PROXY/NO_PROXY variables are honoured.
+	ProxyURL string `json:"proxy_url"`
+	// CABundle is a PEM file with extra root certificates to trust
+	CABundle string `json:"ca_bundle"`
+	// DisableHTTP2 forces HTTP/1.1
+	DisableHTTP2 bool `json:"disable_http2"`
+
+	// ProxyPool rotates requests between several proxies. It is only used for
+	// hosts without a fixed ProxyURL and cannot be overridden per host.
+	ProxyPool ProxyPoolConfig `json:"proxy_pool"`
+
+	// Hosts holds per-host overrides. A key matches the host itself and its subdomains.
+	Hosts map[string]Config `json:"hosts,omitempty"`
+
+	// TTLPolicy decides cache expirations. It is loaded from its own file, named by
+	// FETCHER_TTL_POLICY_FILE, rather than from the fetcher config file.
+	TTLPolicy TTLPolicy `json:"-"`
+}
+
+// DefaultConfig returns the settings used when nothing is configured
+func DefaultConfig() Config {
+	return Config{
+		UserAgent:             defaultUserAgent,
+		Timeout:               Duration(60 * time.Second),
+		DialTimeout:           Duration(10 * time.Second),
+		TLSHandshakeTimeout:   Duration(10 * time.Second),
+		ResponseHeaderTimeout: Duration(30 * time.Second),
+		IdleConnTimeout:       Duration(90 * time.Second),
+		MaxBodySize:           defaultMaxBodySize,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		TTLPolicy:             DefaultTTLPolicy(),
+	}
+}
+
+// LoadConfig builds the fetcher configuration from the defaults, the optional
+// JSON file at path and finally FETCHER_* environment variables. The cache TTL
+// policy is read from the file named by FETCHER_TTL_POLICY_FILE when set.
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to read fetcher config file: %w", err)
+		}
+		var fileConfig Config
+		if err := json.Unmarshal(data, &fileConfig); err != nil {
+			return Config{}, fmt.Errorf("failed to parse fetcher config file: %w", err)
+		}
+		config = config.merge(fileConfig)
+		config.Hosts = normalizeHosts(fileConfig.Hosts)
+		config.ProxyPool = fileConfig.ProxyPool
+	}
+
+	envConfig, err := configFromEnv()
+	if err != nil {
+		return Config{}, err
+	}
+	config = config.merge(envConfig)
+
+	if err := applyProxyPoolEnv(&config.ProxyPool); err != nil {
+		return Config{}, err
+	}
+
+	if policyPath := os.Getenv("FETCHER_TTL_POLICY_FILE"); policyPath != "" {
+		if config.TTLPolicy, err = LoadTTLPolicy(policyPath); err != nil {
+			return Config{}, err
+		}
+	}
+
+	return config, nil
+}
+
+// configFromEnv reads the FETCHER_* environment variables
+func configFromEnv() (Config, error) {
+	var config Config
+	var err error
+
+	config.UserAgent = os.Getenv("FETCHER_USER_AGENT")
+	config.ProxyURL = os.Getenv("FETCHER_PROXY_URL")
+	config.CABundle = os.Getenv("FETCHER_CA_BUNDLE")
+
+	durations := map[string]*Duration{
+		"FETCHER_TIMEOUT":                 &config.Timeout,
+		"FETCHER_DIAL_TIMEOUT":            &config.DialTimeout,
+		"FETCHER_TLS_HANDSHAKE_TIMEOUT":   &config.TLSHandshakeTimeout,
+		"FETCHER_RESPONSE_HEADER_TIMEOUT": &config.ResponseHeaderTimeout,
+		"FETCHER_IDLE_CONN_TIMEOUT":       &config.IdleConnTimeout,
+	}
+	for key, target := range durations {
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+		parsed, parseErr := time.ParseDuration(value)
+		if parseErr != nil {
+			return Config{}, fmt.Errorf("invalid %s: %w", key, parseErr)
+		}
+		*target = Duration(parsed)
+	}
+
+	if value := os.Getenv("FETCHER_MAX_BODY_SIZE"); value != "" {
+		if config.MaxBodySize, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return Config{}, fmt.Errorf("invalid FETCHER_MAX_BODY_SIZE: %w", err)
+		}
+	}
+
+	ints := map[string]*int{
+		"FETCHER_MAX_IDLE_CONNS":          &config.MaxIdleConns,
+		"FETCHER_MAX_IDLE_CONNS_PER_HOST": &config.MaxIdleConnsPerHost,
+		"FETCHER_MAX_CONNS_PER_HOST":      &config.MaxConnsPerHost,
+	}
+	for key, target := range ints {
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+		if *target, err = strconv.Atoi(value); err != nil {
+			return Config{}, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+
+	if value := os.Getenv("FETCHER_DISABLE_HTTP2"); value != "" {
+		if config.DisableHTTP2, err = strconv.ParseBool(value); err != nil {
+			return Config{}, fmt.Errorf("invalid FETCHER_DISABLE_HTTP2: %w", err)
+		}
+	}
+
+	return config, nil
+}
+
+// applyProxyPoolEnv overrides the proxy pool settings from FETCHER_PROXY_POOL_* variables
+func applyProxyPoolEnv(pool *ProxyPoolConfig) error {
+	if value := os.Getenv("FETCHER_PROXY_POOL"); value != "" {
+		pool.Proxies = nil
+		for _, proxy := range strings.Split(value, ",") {
+			if proxy = strings.TrimSpace(proxy); proxy != "" {
+				pool.Proxies = append(pool.Proxies, proxy)
+			}
+		}
+	}
+	if value := os.Getenv("FETCHER_PROXY_POOL_STRATEGY"); value != "" {
+		pool.Strategy = ProxyStrategy(value)
+	}
+	if value := os.Getenv("FETCHER_PROXY_POOL_MAX_FAILURES"); value != "" {
+		maxFailures, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid FETCHER_PROXY_POOL_MAX_FAILURES: %w", err)
+		}
+		pool.MaxFailures = maxFailures
+	}
+	if value := os.Getenv("FETCHER_PROXY_POOL_COOLDOWN"); value != "" {
+		cooldown, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid FETCHER_PROXY_POOL_COOLDOWN: %w", err)
+		}
+		pool.Cooldown = Duration(cooldown)
+	}
+	return nil
+}
+
+// merge returns c with every non-zero field of override applied on top.
+// Headers are combined, with override winning on conflicts.
+func (c Config) merge(override Config) Config {
+	merged := c
+	if override.UserAgent != "" {
+		merged.UserAgent = override.UserAgent
+	}
+	if len(override.Headers) > 0 {
+		merged.Headers = make(map[string]string, len(c.Headers)+len(override.Headers))
+		for k, v := range c.Headers {
+			merged.Headers[k] = v
+		}
+		for k, v := range override.Headers {
+			merged.Headers[k] = v
+		}
+	}
+	if override.MaxBodySize != 0 {
+		merged.MaxBodySize = override.MaxBodySize
+	}
+	if len(override.MaxBodySizeByType) > 0 {
+		merged.MaxBodySizeByType = make(map[string]int64, len(c.MaxBodySizeByType)+len(override.MaxBodySizeByType))
+		for k, v := range c.MaxBodySizeByType {
+			merged.MaxBodySizeByType[k] = v
+		}
+		for k, v := range override.MaxBodySizeByType {
+			merged.MaxBodySizeByType[k] = v
+		}
+	}
+	if override.Timeout != 0 {
+		merged.Timeout = override.Timeout
+	}
+	if override.DialTimeout != 0 {
+		merged.DialTimeout = override.DialTimeout
+	}
+	if override.TLSHandshakeTimeout != 0 {
+		merged.TLSHandshakeTimeout = override.TLSHandshakeTimeout
+	}
+	if override.ResponseHeaderTimeout != 0 {
+		merged.ResponseHeaderTimeout = override.ResponseHeaderTimeout
+	}
+	if override.IdleConnTimeout != 0 {
+		merged.IdleConnTimeout = override.IdleConnTimeout
+	}
+	if override.MaxIdleConns != 0 {
+		merged.MaxIdleConns = override.MaxIdleConns
+	}
+	if override.MaxIdleConnsPerHost != 0 {
+		merged.MaxIdleConnsPerHost = override.MaxIdleConnsPerHost
+	}
+	if override.MaxConnsPerHost != 0 {
+		merged.MaxConnsPerHost = override.MaxConnsPerHost
+	}
+	if override.ProxyURL != "" {
+		merged.ProxyURL = override.ProxyURL
+	}
+	if override.CABundle != "" {
+		merged.CABundle = override.CABundle
+	}
+	if override.DisableHTTP2 {
+		merged.DisableHTTP2 = true
+	}
+	return merged
+}
+
+// normalizeHosts lowercases the keys of host overrides and drops trailing dots,
+// so they match request hosts the way forHost looks them up
+func normalizeHosts(hosts map[string]Config) map[string]Config {
+	if hosts == nil {
+		return nil
+	}
+	normalized := make(map[string]Config, len(hosts))
+	for host, override := range hosts {
+		normalized[strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")] = override
+	}
+	return normalized
+}
+
+// forHost returns the effective configuration for host and the override key
+// that matched it ("" when the top-level settings apply).
+func (c Config) forHost(host string) (Config, string) {
+	host = strings.ToLower(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	// Prefer the most specific match: "shop.example.com" before "example.com"
+	for candidate := host; candidate != ""; {
+		if override, ok := c.Hosts[candidate]; ok {
+			return c.merge(override), candidate
+		}
+		dot := strings.IndexByte(candidate, '.')
+		if dot < 0 {
+			break
+		}
+		candidate = candidate[dot+1:]
+	}
+	return c, ""
+}
+
+// newClient builds an HTTP client from the configuration
+func (c Config) newClient() (*http.Client, error) {
+	dialer := &net.Dialer{
+		Timeout:   time.Duration(c.DialTimeout),
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   time.Duration(c.TLSHandshakeTimeout),
+		ResponseHeaderTimeout: time.Duration(c.ResponseHeaderTimeout),
+		IdleConnTimeout:       time.Duration(c.IdleConnTimeout),
+		MaxIdleConns:          c.MaxIdleConns,
+		MaxIdleConnsPerHost:   c.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       c.MaxConnsPerHost,
+		ExpectContinueTimeout: 1 * time.Second,
+		ForceAttemptHTTP2:     !c.DisableHTTP2,
+	}
+
+	if c.ProxyURL != "" {
+		proxyURL, err := url.Parse(c.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	} else if len(c.ProxyPool.Proxies) > 0 {
+		// The fetcher picks a proxy from the pool per request and passes it on the context
+		transport.Proxy = proxyFromContext
+	}
+
+	if c.CABundle != "" {
+		pem, err := os.ReadFile(c.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", c.CABundle)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	if c.DisableHTTP2 {
+		// A non-nil, empty map disables the bundled HTTP/2 support
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(c.Timeout),
+	}, nil
+}
@@ -5,26 +5,126 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"golang.org/x/sync/singleflight"
 	"web-crawler-go/internal/core/ports"
 )
 
-// Default cache expiration time
-const defaultCacheExpiration = 730 * time.Hour
+// StatusError is returned when the server answers 404 Not Found or 410 Gone.
+// Bodies of other error statuses are returned but not cached.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	// FromCache is set when the status comes from a cached 404 or 410
+	FromCache bool
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d %s fetching %s", e.StatusCode, http.StatusText(e.StatusCode), e.URL)
+}
 
 type HTTPFetcher struct {
-	cache  ports.CacheService
-	logger ports.Logger
+	cache       ports.CacheService
+	recorder    ports.FetchRecorder // Optional archive of every download
+	config      Config
+	client      *http.Client
+	hostClients map[string]*http.Client // Keyed by the matching Config.Hosts entry
+	proxies     *proxyPool
+	inflight    singleflight.Group // Coalesces concurrent downloads, see flightKey
+	logger      ports.Logger
 }
 
-func NewHTTPFetcher(cache ports.CacheService, logger ports.Logger) *HTTPFetcher {
+func NewHTTPFetcher(cache ports.CacheService, recorder ports.FetchRecorder, config Config, logger ports.Logger) (*HTTPFetcher, error) {
+	// Configs built in code have not been through LoadConfig
+	config.Hosts = normalizeHosts(config.Hosts)
+
+	client, err := config.newClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build HTTP client: %w", err)
+	}
+
+	// Build the per-host clients up front so configuration errors surface at startup
+	hostClients := make(map[string]*http.Client, len(config.Hosts))
+	for host := range config.Hosts {
+		hostConfig, _ := config.forHost(host)
+		hostClient, err := hostConfig.newClient()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build HTTP client for host %s: %w", host, err)
+		}
+		hostClients[host] = hostClient
+	}
+
+	proxies, err := newProxyPool(config.ProxyPool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build proxy pool: %w", err)
+	}
+
+	if err := config.TTLPolicy.compile(); err != nil {
+		return nil, fmt.Errorf("failed to load cache TTL policy: %w", err)
+	}
+
 	return &HTTPFetcher{
-		cache:  cache,
-		logger: logger,
+		cache:       cache,
+		recorder:    recorder,
+		config:      config,
+		client:      client,
+		hostClients: hostClients,
+		proxies:     proxies,
+		logger:      logger,
+	}, nil
+}
+
+// ProxyStatuses implements ports.ProxyMonitor
+func (f *HTTPFetcher) ProxyStatuses() []ports.ProxyStatus {
+	if f.proxies == nil {
+		return []ports.ProxyStatus{}
 	}
+	return f.proxies.statuses()
+}
+
+// do sends the request, routing it through the proxy pool when the host has no fixed proxy
+func (f *HTTPFetcher) do(req *http.Request) (*http.Response, error) {
+	client, hostConfig := f.clientFor(req)
+	for name, value := range hostConfig.Headers {
+		req.Header.Set(name, value)
+	}
+	if hostConfig.UserAgent != "" {
+		req.Header.Set("User-Agent", hostConfig.UserAgent)
+	}
+
+	if f.proxies == nil || hostConfig.ProxyURL != "" {
+		return client.Do(req)
+	}
+
+	proxy := f.proxies.pick(req.URL.Host)
+	resp, err := client.Do(req.WithContext(withProxy(req.Context(), proxy.url)))
+	switch {
+	case err != nil:
+		f.proxies.report(proxy, err)
+	case isProxyFailure(resp.StatusCode):
+		f.proxies.report(proxy, fmt.Errorf("unexpected status %s", resp.Status))
+	default:
+		f.proxies.report(proxy, nil)
+	}
+	if err != nil {
+		f.logger.Warn("request through proxy failed", "proxy", proxy.url.Redacted(), "url", req.URL.String(), "error", err)
+	}
+	return resp, err
+}
+
+// clientFor returns the client and effective configuration for the request's host
+func (f *HTTPFetcher) clientFor(req *http.Request) (*http.Client, Config) {
+	hostConfig, key := f.config.forHost(req.URL.Host)
+	if key == "" {
+		return f.client, hostConfig
+	}
+	return f.hostClients[key], hostConfig
 }
 
 // generateCacheKey creates a unique key for caching based on the URL
@@ -33,65 +133,273 @@ func generateCacheKey(url string) string {
 	return "url:" + hex.EncodeToString(hash[:])
 }
 
+// negativeCacheKey is where a cached 404 or 410 for the same URL is kept
+func negativeCacheKey(cacheKey string) string {
+	return "neg:" + strings.TrimPrefix(cacheKey, "url:")
+}
+
+func isNotFound(statusCode int) bool {
+	return statusCode == http.StatusNotFound || statusCode == http.StatusGone
+}
+
+// cachedNotFound returns a StatusError when the URL recently answered 404 or 410
+func (f *HTTPFetcher) cachedNotFound(ctx context.Context, cacheKey, url string) error {
+	cached, found, err := f.cache.Get(ctx, negativeCacheKey(cacheKey))
+	if err != nil || !found {
+		return nil
+	}
+	defer cached.Close()
+
+	data, err := ports.ReadAll(cached)
+	if err != nil {
+		return nil
+	}
+	statusCode, err := strconv.Atoi(string(data))
+	if err != nil {
+		statusCode = http.StatusNotFound
+	}
+	f.logger.Info("negative cache hit", "url", url, "status", statusCode)
+	return &StatusError{URL: url, StatusCode: statusCode, FromCache: true}
+}
+
+// fetchedBody is a fully buffered body returned by Fetch along with metadata about the fetch
+type fetchedBody struct {
+	*bytes.Reader
+	data     []byte
+	metadata ports.FetchMetadata
+}
+
+func newFetchedBody(data []byte, metadata ports.FetchMetadata) *fetchedBody {
+	return &fetchedBody{Reader: bytes.NewReader(data), data: data, metadata: metadata}
+}
+
+// Close implements io.Closer; the body is already fully buffered
+func (b *fetchedBody) Close() error {
+	return nil
+}
+
+// Bytes implements ports.BufferedBody
+func (b *fetchedBody) Bytes() []byte {
+	return b.data[len(b.data)-b.Len():]
+}
+
+// FetchMetadata implements ports.FetchMetadataProvider
+func (b *fetchedBody) FetchMetadata() ports.FetchMetadata {
+	return b.metadata
+}
+
+// Fetch downloads url, or serves it from the cache, and returns the body transcoded to UTF-8.
+// The cache keeps the bytes as served so the original charset is detected again on a hit,
+// from the cached Content-Type when the backend stores response metadata and otherwise
+// from the byte order mark and the document itself.
 func (f *HTTPFetcher) Fetch(ctx context.Context, url string) (io.ReadCloser, error) {
 	// Generate cache key
 	cacheKey := generateCacheKey(url)
+	options := ports.FetchOptionsFromContext(ctx)
+	cacheMode := options.CacheMode
 
-	// Try to get from cache first
-	if f.cache != nil {
+	// Try to get from cache first, unless the crawl asked for fresh data
+	if f.cache != nil && cacheMode.ReadsCache() {
 		cachedData, found, err := f.cache.Get(ctx, cacheKey)
 		if err != nil {
 			f.logger.Error("cache get error", "error", err)
 		} else if found {
 			f.logger.Info("cache hit", "key", cacheKey)
-			return cachedData, nil
+			// Backends that keep response metadata let us reuse the original Content-Type
+			var contentType string
+			if provider, ok := cachedData.(ports.CacheEntryMetadataProvider); ok {
+				contentType = provider.CacheEntryMetadata().Header.Get("Content-Type")
+			}
+			bodyBytes, err := ports.ReadAll(cachedData)
+			cachedData.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read cached body: %w", err)
+			}
+			return f.decode(url, bodyBytes, contentType, true)
+		}
+
+		if statusErr := f.cachedNotFound(ctx, cacheKey, url); statusErr != nil {
+			return nil, statusErr
+		}
+	}
+
+	// Concurrent fetches of the same URL share one download. The shared request is
+	// detached from any single caller's cancellation so one caller giving up does not
+	// fail the others; each caller still stops waiting when its own context ends.
+	resultChan := f.inflight.DoChan(flightKey(cacheKey, options), func() (interface{}, error) {
+		return f.download(context.WithoutCancel(ctx), url, cacheKey)
+	})
+
+	select {
+	case result := <-resultChan:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		if result.Shared {
+			f.logger.Debug("shared in-flight fetch", "url", url)
 		}
+		// Every caller gets its own reader over the same immutable body
+		fetched := result.Val.(*fetchResult)
+		return f.decode(url, fetched.body, fetched.contentType, false)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// flightKey identifies the downloads that may be shared. The download runs with the
+// first caller's fetch options, which pick the cache TTL and tag the WARC record with
+// the crawl run, so only callers with the same options share it.
+func flightKey(cacheKey string, options ports.FetchOptions) string {
+	cacheMode := options.CacheMode
+	if cacheMode == "" {
+		cacheMode = ports.CacheModeUse
 	}
+	return strings.Join([]string{cacheKey, string(cacheMode), options.Provider, options.RunID}, "|")
+}
 
-	f.logger.Info("cache miss, making HTTP request", "url", url)
+// fetchResult is the raw result of a network fetch, shared between coalesced callers
+type fetchResult struct {
+	body        []byte
+	contentType string
+}
+
+// download makes the HTTP request for url and stores the body in the cache
+func (f *HTTPFetcher) download(ctx context.Context, url, cacheKey string) (*fetchResult, error) {
+	options := ports.FetchOptionsFromContext(ctx)
+	f.logger.Info("cache miss, making HTTP request", "url", url, "cacheMode", options.CacheMode)
 	// If not in cache or cache error, make HTTP request
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := f.do(req)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	// If we have a cache, store the response
-	if f.cache != nil {
-		// We need to read the body to store it in cache
-		// and then provide it to the caller
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err != nil {
-			// If we can't read the body, just return the original response
-			return resp.Body, nil
+	contentType := resp.Header.Get("Content-Type")
+	hostConfig, _ := f.config.forHost(req.URL.Host)
+	limit := hostConfig.maxBodySizeFor(contentType)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		f.logger.Warn("unexpected response status", "url", url, "status", resp.StatusCode)
+	}
+	if isNotFound(resp.StatusCode) {
+		if f.recorder != nil {
+			// Missing pages are archived too; a body over the limit is recorded empty
+			errorBody, _ := readLimited(resp.Body, limit, url, contentType)
+			f.record(ctx, req, resp, errorBody)
+		}
+		// Remember missing pages for a while so every crawl does not ask again
+		notFoundTTL := time.Duration(f.config.TTLPolicy.NotFoundTTL)
+		if f.cache != nil && options.CacheMode.WritesCache() && notFoundTTL > 0 {
+			f.storeAsync(negativeCacheKey(cacheKey), newFetchedBody([]byte(strconv.Itoa(resp.StatusCode)), ports.FetchMetadata{URL: url}), notFoundTTL,
+				ports.CacheEntryMetadata{
+					URL:        url,
+					StatusCode: resp.StatusCode,
+					Header:     resp.Header.Clone(),
+					FetchedAt:  time.Now(),
+				})
 		}
+		return nil, &StatusError{URL: url, StatusCode: resp.StatusCode}
+	}
+
+	// Refuse early when the server announces an oversized body
+	if limit > 0 && resp.ContentLength > limit {
+		f.logger.Error("response too large", "url", url, "contentLength", resp.ContentLength, "limit", limit)
+		return nil, &ResponseTooLargeError{URL: url, ContentType: contentType, Limit: limit}
+	}
+
+	// The body is read once, within the limit, and that buffer is shared by the
+	// cache write and every caller
+	bodyBytes, err := readLimited(resp.Body, limit, url, contentType)
+	if err != nil {
+		f.logger.Error("failed to read response body", "url", url, "error", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	f.record(ctx, req, resp, bodyBytes)
 
-		// Close the original body
-		resp.Body.Close()
+	// If we have a cache, store the response for as long as the TTL policy allows
+	if f.cache != nil && options.CacheMode.WritesCache() {
+		if ttl, cacheable := f.config.TTLPolicy.ttlFor(url, contentType, options.Provider, resp.StatusCode, resp.Header); cacheable {
+			f.storeAsync(cacheKey, newFetchedBody(bodyBytes, ports.FetchMetadata{URL: url, ContentType: contentType}), ttl,
+				ports.CacheEntryMetadata{
+					URL:        url,
+					StatusCode: resp.StatusCode,
+					Header:     resp.Header.Clone(),
+					FetchedAt:  time.Now(),
+				})
+		} else {
+			f.logger.Debug("response not cacheable", "url", url)
+		}
+	}
 
-		// Create two readers: one for cache, one to return
-		cacheReader := io.NopCloser(bytes.NewReader(bodyBytes))
-		returnReader := io.NopCloser(bytes.NewReader(bodyBytes))
+	return &fetchResult{body: bodyBytes, contentType: contentType}, nil
+}
 
-		// Store in cache asynchronously to not block the response
-		go func() {
-			cacheCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
+// record archives the exchange when a recorder is configured. Failures are logged
+// and do not fail the fetch.
+func (f *HTTPFetcher) record(ctx context.Context, req *http.Request, resp *http.Response, body []byte) {
+	if f.recorder == nil {
+		return
+	}
 
-			f.logger.Info("setting cache", "key", cacheKey)
-			err := f.cache.Set(cacheCtx, cacheKey, cacheReader, defaultCacheExpiration)
-			if err != nil {
-				f.logger.Error("cache set error", "error", err)
-			}
-		}()
+	err := f.recorder.Record(ctx, ports.RecordedExchange{
+		RunID:          ports.FetchOptionsFromContext(ctx).RunID,
+		URL:            req.URL.String(),
+		Method:         req.Method,
+		RequestHeader:  req.Header.Clone(),
+		Proto:          resp.Proto,
+		StatusCode:     resp.StatusCode,
+		Status:         resp.Status,
+		ResponseHeader: resp.Header.Clone(),
+		Body:           body,
+		FetchedAt:      time.Now(),
+	})
+	if err != nil {
+		f.logger.Error("failed to record exchange", "url", req.URL.String(), "error", err)
+	}
+}
+
+// storeAsync writes a cache entry in the background so the caller is not blocked.
+// The entry is tagged with its domain for InvalidateDomain.
+func (f *HTTPFetcher) storeAsync(key string, body io.ReadCloser, ttl time.Duration, metadata ports.CacheEntryMetadata) {
+	go func() {
+		cacheCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		cacheCtx = ports.WithCacheEntryMetadata(cacheCtx, metadata)
+		cacheCtx = ports.WithCacheTags(cacheCtx, domainTags(metadata.URL)...)
+
+		f.logger.Info("setting cache", "key", key, "ttl", ttl.String())
+		if err := f.cache.Set(cacheCtx, key, body, ttl); err != nil {
+			f.logger.Error("cache set error", "error", err)
+		}
+	}()
+}
 
-		return returnReader, nil
+// decode transcodes a raw body to UTF-8 and wraps it with its fetch metadata
+func (f *HTTPFetcher) decode(url string, raw []byte, contentType string, fromCache bool) (io.ReadCloser, error) {
+	decoded, charsetName, err := transcodeToUTF8(raw, contentType)
+	if err != nil {
+		f.logger.Error("failed to transcode body", "url", url, "charset", charsetName, "error", err)
+		return nil, err
+	}
+	if charsetName != "utf-8" {
+		f.logger.Info("transcoded body to UTF-8", "url", url, "charset", charsetName)
 	}
 
-	// Caller is responsible for closing the body
-	return resp.Body, nil
+	return newFetchedBody(decoded, ports.FetchMetadata{
+		URL:         url,
+		ContentType: contentType,
+		Charset:     charsetName,
+		FromCache:   fromCache,
+	}), nil
 }
+
+// Ensure HTTPFetcher implements HTMLFetcher and ProxyMonitor
+var (
+	_ ports.HTMLFetcher  = (*HTTPFetcher)(nil)
+	_ ports.ProxyMonitor = (*HTTPFetcher)(nil)
+)
@@ -0,0 +1,149 @@
+package fetcher
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"web-crawler-go/internal/core/ports"
+	"web-crawler-go/internal/core/services/loggerservice"
+)
+
+// stubRecorder remembers the crawl run of every recorded exchange
+type stubRecorder struct {
+	mu     sync.Mutex
+	runIDs []string
+}
+
+func (r *stubRecorder) Record(ctx context.Context, exchange ports.RecordedExchange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runIDs = append(r.runIDs, exchange.RunID)
+	return nil
+}
+
+// blockingServer answers every request with body once release is closed, and
+// signals arrived on its first request
+func blockingServer(t *testing.T, body string) (server *httptest.Server, hits *atomic.Int32, arrived, release chan struct{}) {
+	t.Helper()
+	hits = &atomic.Int32{}
+	arrived = make(chan struct{})
+	release = make(chan struct{})
+	var once sync.Once
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		once.Do(func() { close(arrived) })
+		<-release
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server, hits, arrived, release
+}
+
+// fetchConcurrently fetches url once per context and returns the bodies in order
+func fetchConcurrently(t *testing.T, f *HTTPFetcher, url string, contexts []context.Context, arrived, release chan struct{}) []io.ReadCloser {
+	t.Helper()
+	bodies := make([]io.ReadCloser, len(contexts))
+	errs := make([]error, len(contexts))
+	var wg sync.WaitGroup
+	for i, ctx := range contexts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bodies[i], errs[i] = f.Fetch(ctx, url)
+		}()
+	}
+
+	// Give every caller time to reach the in-flight download before it completes
+	<-arrived
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("fetch %d: %v", i, err)
+		}
+	}
+	return bodies
+}
+
+func TestFetchCoalescesConcurrentDownloads(t *testing.T) {
+	const callers = 8
+	const page = "<html><body>Oolong tea</body></html>"
+	server, hits, arrived, release := blockingServer(t, page)
+
+	recorder := &stubRecorder{}
+	f, err := NewHTTPFetcher(nil, recorder, DefaultConfig(), loggerservice.NewLoggerService())
+	if err != nil {
+		t.Fatalf("NewHTTPFetcher: %v", err)
+	}
+
+	ctx := ports.WithFetchOptions(context.Background(), ports.FetchOptions{Provider: "shopline.tw", RunID: "run-1"})
+	contexts := make([]context.Context, callers)
+	for i := range contexts {
+		contexts[i] = ctx
+	}
+	bodies := fetchConcurrently(t, f, server.URL, contexts, arrived, release)
+
+	if got := hits.Load(); got != 1 {
+		t.Errorf("upstream hits = %d, want 1", got)
+	}
+	if len(recorder.runIDs) != 1 {
+		t.Errorf("recorded %d exchanges, want 1", len(recorder.runIDs))
+	}
+
+	// Draining one reader leaves the others untouched
+	for i, body := range bodies {
+		for _, other := range bodies[:i] {
+			if body == other {
+				t.Fatalf("callers share a reader")
+			}
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatalf("read body %d: %v", i, err)
+		}
+		if string(data) != page {
+			t.Errorf("body %d = %q, want %q", i, data, page)
+		}
+		body.Close()
+	}
+}
+
+func TestFetchDoesNotShareDownloadsAcrossOptions(t *testing.T) {
+	server, hits, arrived, release := blockingServer(t, "<html></html>")
+
+	recorder := &stubRecorder{}
+	f, err := NewHTTPFetcher(nil, recorder, DefaultConfig(), loggerservice.NewLoggerService())
+	if err != nil {
+		t.Fatalf("NewHTTPFetcher: %v", err)
+	}
+
+	background := context.Background()
+	contexts := []context.Context{
+		ports.WithFetchOptions(background, ports.FetchOptions{Provider: "shopline.tw", RunID: "run-1"}),
+		ports.WithFetchOptions(background, ports.FetchOptions{Provider: "shopline.tw", RunID: "run-2"}),
+		ports.WithFetchOptions(background, ports.FetchOptions{Provider: "shopline.tw", RunID: "run-1", CacheMode: ports.CacheModeBypass}),
+		// An empty cache mode is CacheModeUse, so this one shares run-1's download
+		ports.WithFetchOptions(background, ports.FetchOptions{Provider: "shopline.tw", RunID: "run-1", CacheMode: ports.CacheModeUse}),
+	}
+	for _, body := range fetchConcurrently(t, f, server.URL, contexts, arrived, release) {
+		body.Close()
+	}
+
+	if got := hits.Load(); got != 3 {
+		t.Errorf("upstream hits = %d, want 3", got)
+	}
+	slices.Sort(recorder.runIDs)
+	if want := []string{"run-1", "run-1", "run-2"}; !slices.Equal(recorder.runIDs, want) {
+		t.Errorf("recorded runs = %v, want %v", recorder.runIDs, want)
+	}
+}
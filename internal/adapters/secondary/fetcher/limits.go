@@ -0,0 +1,70 @@
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+)
+
+// defaultMaxBodySize applies to content types without a specific limit
+const defaultMaxBodySize = 20 << 20 // 20 MiB
+
+// ErrResponseTooLarge is returned when a response body exceeds the configured size limit
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// ResponseTooLargeError reports which response exceeded which limit.
+// It matches ErrResponseTooLarge with errors.Is.
+type ResponseTooLargeError struct {
+	URL         string
+	ContentType string
+	Limit       int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("%s: %s (%s) exceeds %d bytes", ErrResponseTooLarge, e.URL, e.ContentType, e.Limit)
+}
+
+// Is makes errors.Is(err, ErrResponseTooLarge) match
+func (e *ResponseTooLargeError) Is(target error) bool {
+	return target == ErrResponseTooLarge
+}
+
+// maxBodySizeFor returns the limit for a content type. Limits are looked up by exact
+// media type ("application/json"), then by wildcard ("image/*"), then the default.
+func (c Config) maxBodySizeFor(contentType string) int64 {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		if limit, ok := c.MaxBodySizeByType[mediaType]; ok {
+			return limit
+		}
+		if slash := strings.IndexByte(mediaType, '/'); slash > 0 {
+			if limit, ok := c.MaxBodySizeByType[mediaType[:slash]+"/*"]; ok {
+				return limit
+			}
+		}
+	}
+	if c.MaxBodySize > 0 {
+		return c.MaxBodySize
+	}
+	return defaultMaxBodySize
+}
+
+// readLimited reads body fully, failing once more than limit bytes arrive.
+// A limit of zero or less means no limit.
+func readLimited(body io.Reader, limit int64, url, contentType string) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(body)
+	}
+
+	// Read one byte past the limit to tell "exactly at the limit" from "over it"
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, &ResponseTooLargeError{URL: url, ContentType: contentType, Limit: limit}
+	}
+	return data, nil
+}
@@ -0,0 +1,128 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"web-crawler-go/internal/core/services/loggerservice"
+)
+
+func TestReadLimited(t *testing.T) {
+	const limit = 16
+	tests := []struct {
+		name    string
+		size    int
+		limit   int64
+		wantErr bool
+	}{
+		{name: "under the limit", size: limit - 1, limit: limit},
+		{name: "at the limit", size: limit, limit: limit},
+		{name: "one byte over", size: limit + 1, limit: limit, wantErr: true},
+		{name: "no limit", size: 4 * limit, limit: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := bytes.Repeat([]byte("a"), tt.size)
+			data, err := readLimited(bytes.NewReader(body), tt.limit, "https://shop.example.tw/", "text/html")
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("readLimited: %v", err)
+				}
+				if !bytes.Equal(data, body) {
+					t.Errorf("read %d bytes, want %d", len(data), len(body))
+				}
+				return
+			}
+
+			var tooLarge *ResponseTooLargeError
+			if !errors.As(err, &tooLarge) || !errors.Is(err, ErrResponseTooLarge) {
+				t.Fatalf("error = %v, want a ResponseTooLargeError", err)
+			}
+			if tooLarge.Limit != tt.limit || tooLarge.URL != "https://shop.example.tw/" || tooLarge.ContentType != "text/html" {
+				t.Errorf("error = %+v, want the URL, content type and limit", tooLarge)
+			}
+		})
+	}
+}
+
+func TestMaxBodySizeFor(t *testing.T) {
+	config := Config{
+		MaxBodySize: 100,
+		MaxBodySizeByType: map[string]int64{
+			"application/json": 10,
+			"image/*":          20,
+		},
+	}
+	tests := []struct {
+		contentType string
+		want        int64
+	}{
+		{contentType: "application/json; charset=utf-8", want: 10},
+		{contentType: "image/png", want: 20},
+		{contentType: "text/html", want: 100},
+		{contentType: "", want: 100},
+	}
+	for _, tt := range tests {
+		if got := config.maxBodySizeFor(tt.contentType); got != tt.want {
+			t.Errorf("maxBodySizeFor(%q) = %d, want %d", tt.contentType, got, tt.want)
+		}
+	}
+
+	if got := (Config{}).maxBodySizeFor("text/html"); got != defaultMaxBodySize {
+		t.Errorf("default limit = %d, want %d", got, defaultMaxBodySize)
+	}
+}
+
+func TestFetchRejectsOversizedBodies(t *testing.T) {
+	const limit = 32
+	tests := []struct {
+		name    string
+		size    int
+		chunked bool
+		wantErr bool
+	}{
+		{name: "at the limit", size: limit},
+		{name: "announced over the limit", size: limit + 1, wantErr: true},
+		{name: "streamed over the limit", size: limit + 1, chunked: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				body := strings.Repeat("a", tt.size)
+				if tt.chunked {
+					// Flushing before the body hides its length from the client
+					w.(http.Flusher).Flush()
+				}
+				w.Write([]byte(body))
+			}))
+			defer server.Close()
+
+			config := DefaultConfig()
+			config.MaxBodySize = limit
+			f, err := NewHTTPFetcher(nil, nil, config, loggerservice.NewLoggerService())
+			if err != nil {
+				t.Fatalf("NewHTTPFetcher: %v", err)
+			}
+
+			body, err := f.Fetch(context.Background(), server.URL)
+			if tt.wantErr {
+				if !errors.Is(err, ErrResponseTooLarge) {
+					t.Fatalf("error = %v, want ErrResponseTooLarge", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			body.Close()
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"web-crawler-go/internal/core/ports"
+)
+
+// ProxyStrategy selects how the pool picks a proxy for a request
+type ProxyStrategy string
+
+const (
+	// ProxyStrategyRoundRobin cycles through the healthy proxies
+	ProxyStrategyRoundRobin ProxyStrategy = "round_robin"
+	// ProxyStrategySticky keeps using the same proxy for a host while it stays healthy
+	ProxyStrategySticky ProxyStrategy = "sticky"
+	// ProxyStrategyLeastFailures prefers the healthy proxy with the fewest failures so far
+	ProxyStrategyLeastFailures ProxyStrategy = "least_failures"
+)
+
+// ProxyPoolConfig configures rotation between several outbound proxies
+type ProxyPoolConfig struct {
+	Proxies  []string      `json:"proxies"`
+	Strategy ProxyStrategy `json:"strategy"`
+	// MaxFailures is the number of consecutive failures before a proxy is benched
+	MaxFailures int `json:"max_failures"`
+	// Cooldown is how long a benched proxy is skipped
+	Cooldown Duration `json:"cooldown"`
+}
+
+type proxyContextKey struct{}
+
+// withProxy stores the proxy chosen for a request in its context
+func withProxy(ctx context.Context, proxy *url.URL) context.Context {
+	return context.WithValue(ctx, proxyContextKey{}, proxy)
+}
+
+// proxyFromContext is used as Transport.Proxy when a pool is configured.
+// Requests without a pool-assigned proxy fall back to the environment settings.
+func proxyFromContext(req *http.Request) (*url.URL, error) {
+	if proxy, ok := req.Context().Value(proxyContextKey{}).(*url.URL); ok && proxy != nil {
+		return proxy, nil
+	}
+	return http.ProxyFromEnvironment(req)
+}
+
+// proxyState tracks the health of one proxy in the pool
+type proxyState struct {
+	url                 *url.URL
+	consecutiveFailures int
+	totalRequests       int64
+	totalFailures       int64
+	lastError           string
+	lastFailureAt       time.Time
+	cooldownUntil       time.Time
+}
+
+// proxyPool rotates requests between proxies and benches the ones that keep failing
+type proxyPool struct {
+	mu          sync.Mutex
+	proxies     []*proxyState
+	strategy    ProxyStrategy
+	maxFailures int
+	cooldown    time.Duration
+	next        int
+	sticky      map[string]*proxyState // Keyed by host
+	now         func() time.Time
+}
+
+func newProxyPool(config ProxyPoolConfig) (*proxyPool, error) {
+	if len(config.Proxies) == 0 {
+		return nil, nil
+	}
+
+	strategy := config.Strategy
+	switch strategy {
+	case "":
+		strategy = ProxyStrategyRoundRobin
+	case ProxyStrategyRoundRobin, ProxyStrategySticky, ProxyStrategyLeastFailures:
+	default:
+		return nil, fmt.Errorf("unknown proxy strategy %q", config.Strategy)
+	}
+
+	maxFailures := config.MaxFailures
+	if maxFailures <= 0 {
+		maxFailures = 3
+	}
+	cooldown := time.Duration(config.Cooldown)
+	if cooldown <= 0 {
+		cooldown = time.Minute
+	}
+
+	proxies := make([]*proxyState, 0, len(config.Proxies))
+	for _, raw := range config.Proxies {
+		proxyURL, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+		}
+		proxies = append(proxies, &proxyState{url: proxyURL})
+	}
+
+	return &proxyPool{
+		proxies:     proxies,
+		strategy:    strategy,
+		maxFailures: maxFailures,
+		cooldown:    cooldown,
+		sticky:      make(map[string]*proxyState),
+		now:         time.Now,
+	}, nil
+}
+
+// pick chooses the proxy for a request to host
+func (p *proxyPool) pick(host string) *proxyState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+
+	if p.strategy == ProxyStrategySticky {
+		if proxy, ok := p.sticky[host]; ok && proxy.healthy(now) {
+			return proxy
+		}
+	}
+
+	var chosen *proxyState
+	switch p.strategy {
+	case ProxyStrategyLeastFailures:
+		for _, proxy := range p.proxies {
+			if !proxy.healthy(now) {
+				continue
+			}
+			if chosen == nil ||
+				proxy.totalFailures < chosen.totalFailures ||
+				(proxy.totalFailures == chosen.totalFailures && proxy.totalRequests < chosen.totalRequests) {
+				chosen = proxy
+			}
+		}
+	default:
+		for i := 0; i < len(p.proxies); i++ {
+			proxy := p.proxies[(p.next+i)%len(p.proxies)]
+			if proxy.healthy(now) {
+				chosen = proxy
+				p.next = (p.next + i + 1) % len(p.proxies)
+				break
+			}
+		}
+	}
+
+	// Every proxy is cooling down: use the one that comes back first rather than failing the fetch
+	if chosen == nil {
+		for _, proxy := range p.proxies {
+			if chosen == nil || proxy.cooldownUntil.Before(chosen.cooldownUntil) {
+				chosen = proxy
+			}
+		}
+	}
+
+	if p.strategy == ProxyStrategySticky {
+		p.sticky[host] = chosen
+	}
+	chosen.totalRequests++
+	return chosen
+}
+
+// report records the outcome of a request made through proxy
+func (p *proxyPool) report(proxy *proxyState, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err == nil {
+		proxy.consecutiveFailures = 0
+		proxy.cooldownUntil = time.Time{}
+		return
+	}
+
+	now := p.now()
+	proxy.consecutiveFailures++
+	proxy.totalFailures++
+	proxy.lastError = err.Error()
+	proxy.lastFailureAt = now
+	if proxy.consecutiveFailures >= p.maxFailures {
+		proxy.cooldownUntil = now.Add(p.cooldown)
+	}
+}
+
+// statuses returns a snapshot of the pool health
+func (p *proxyPool) statuses() []ports.ProxyStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	statuses := make([]ports.ProxyStatus, 0, len(p.proxies))
+	for _, proxy := range p.proxies {
+		statuses = append(statuses, ports.ProxyStatus{
+			URL:                 proxy.url.Redacted(),
+			Healthy:             proxy.healthy(now),
+			ConsecutiveFailures: proxy.consecutiveFailures,
+			TotalRequests:       proxy.totalRequests,
+			TotalFailures:       proxy.totalFailures,
+			LastError:           proxy.lastError,
+			LastFailureAt:       proxy.lastFailureAt,
+			CooldownUntil:       proxy.cooldownUntil,
+		})
+	}
+	return statuses
+}
+
+func (s *proxyState) healthy(now time.Time) bool {
+	return !now.Before(s.cooldownUntil)
+}
+
+// isProxyFailure reports whether a response status points at the proxy being blocked or throttled
+func isProxyFailure(statusCode int) bool {
+	switch statusCode {
+	case http.StatusForbidden, http.StatusProxyAuthRequired, http.StatusTooManyRequests:
+		return true
+	}
+	return false
+}
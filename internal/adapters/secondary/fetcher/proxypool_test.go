@@ -0,0 +1,115 @@
+package fetcher
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"web-crawler-go/internal/core/services/loggerservice"
+)
+
+// newForwardProxy starts a stand-in forward proxy that answers every request
+// itself with the status, naming itself in the X-Proxy header
+func newForwardProxy(t *testing.T, name string, status int) *httptest.Server {
+	t.Helper()
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proxy", name)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy
+}
+
+func TestProxyPoolRotatesEjectsAndReadmits(t *testing.T) {
+	healthy := newForwardProxy(t, "healthy", http.StatusOK)
+	failing := newForwardProxy(t, "failing", http.StatusForbidden)
+
+	config := DefaultConfig()
+	config.ProxyPool = ProxyPoolConfig{
+		Proxies:     []string{healthy.URL, failing.URL},
+		Strategy:    ProxyStrategyRoundRobin,
+		MaxFailures: 2,
+		Cooldown:    Duration(time.Minute),
+	}
+	f, err := NewHTTPFetcher(nil, nil, config, loggerservice.NewLoggerService())
+	if err != nil {
+		t.Fatalf("NewHTTPFetcher: %v", err)
+	}
+
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	f.proxies.now = func() time.Time { return now }
+
+	fetchVia := func() string {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, "http://shop.example.test/products", nil)
+		if err != nil {
+			t.Fatalf("NewRequest: %v", err)
+		}
+		resp, err := f.do(req)
+		if err != nil {
+			t.Fatalf("do: %v", err)
+		}
+		resp.Body.Close()
+		return resp.Header.Get("X-Proxy")
+	}
+	expectVia := func(step string, want ...string) {
+		t.Helper()
+		for i, name := range want {
+			if got := fetchVia(); got != name {
+				t.Fatalf("%s: request %d went through %q, want %q", step, i+1, got, name)
+			}
+		}
+	}
+
+	// Round robin until the failing proxy reaches MaxFailures
+	expectVia("rotation", "healthy", "failing", "healthy", "failing")
+
+	statuses := f.ProxyStatuses()
+	if !statuses[0].Healthy || statuses[1].Healthy {
+		t.Fatalf("after two failures: healthy = %v, %v; want true, false", statuses[0].Healthy, statuses[1].Healthy)
+	}
+	if statuses[1].ConsecutiveFailures != 2 || statuses[1].TotalFailures != 2 {
+		t.Fatalf("failing proxy failures = %d consecutive, %d total; want 2, 2",
+			statuses[1].ConsecutiveFailures, statuses[1].TotalFailures)
+	}
+
+	// Ejected: every request goes through the healthy proxy
+	expectVia("ejection", "healthy", "healthy", "healthy")
+
+	// Still cooling down just before the cooldown ends
+	now = now.Add(time.Minute - time.Second)
+	expectVia("cooldown", "healthy")
+
+	// Re-admitted once the cooldown has passed
+	now = now.Add(time.Second)
+	if !f.ProxyStatuses()[1].Healthy {
+		t.Fatal("failing proxy not healthy after the cooldown")
+	}
+	seen := map[string]bool{}
+	for range 2 {
+		seen[fetchVia()] = true
+	}
+	if !seen["failing"] || !seen["healthy"] {
+		t.Fatalf("after the cooldown requests went through %v, want both proxies", seen)
+	}
+}
+
+func TestProxyPoolSuccessResetsFailures(t *testing.T) {
+	pool, err := newProxyPool(ProxyPoolConfig{Proxies: []string{"http://proxy.test:3128"}, MaxFailures: 2})
+	if err != nil {
+		t.Fatalf("newProxyPool: %v", err)
+	}
+
+	proxy := pool.pick("shop.example.test")
+	pool.report(proxy, errTest)
+	pool.report(proxy, nil)
+	pool.report(proxy, errTest)
+
+	if status := pool.statuses()[0]; !status.Healthy || status.ConsecutiveFailures != 1 {
+		t.Fatalf("status = healthy %v, %d consecutive failures; want healthy with 1", status.Healthy, status.ConsecutiveFailures)
+	}
+}
+
+var errTest = errors.New("proxy refused the connection")
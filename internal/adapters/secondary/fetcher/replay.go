@@ -0,0 +1,206 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"web-crawler-go/internal/adapters/secondary/warc"
+	"web-crawler-go/internal/core/ports"
+)
+
+// fixtureIndexFile maps URLs to files in a fixture directory
+const fixtureIndexFile = "index.json"
+
+// ErrNotRecorded is returned by ReplayFetcher for URLs missing from the archive
+var ErrNotRecorded = errors.New("URL not recorded in replay archive")
+
+// fixture is one entry of a fixture directory's index.json
+type fixture struct {
+	File        string      `json:"file"`
+	StatusCode  int         `json:"status_code,omitempty"`
+	ContentType string      `json:"content_type,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+}
+
+// replayedResponse is a recorded response ready to be served
+type replayedResponse struct {
+	statusCode  int
+	contentType string
+	body        []byte
+	recordedAt  time.Time
+}
+
+// ReplayFetcher implements HTMLFetcher by serving responses from a recording
+// instead of the network, so a crawl can be repeated against a frozen snapshot.
+// Any URL that was not recorded fails with ErrNotRecorded.
+type ReplayFetcher struct {
+	responses map[string]*replayedResponse
+	logger    ports.Logger
+}
+
+// NewReplayFetcher loads a recording from path: a WARC file, a directory of WARC
+// files, or a fixture directory with an index.json mapping each URL to a file.
+// When runID is set only WARC records from that crawl run are used; if a URL was
+// recorded several times the latest response wins.
+func NewReplayFetcher(path, runID string, logger ports.Logger) (*ReplayFetcher, error) {
+	f := &ReplayFetcher{
+		responses: make(map[string]*replayedResponse),
+		logger:    logger,
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replay source: %w", err)
+	}
+
+	switch {
+	case !info.IsDir():
+		err = f.loadWARC([]string{path}, runID)
+	case fileExists(filepath.Join(path, fixtureIndexFile)):
+		err = f.loadFixtures(path)
+	default:
+		var files []string
+		for _, pattern := range []string{"*.warc", "*.warc.gz"} {
+			matches, _ := filepath.Glob(filepath.Join(path, pattern))
+			files = append(files, matches...)
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("replay directory %s has neither WARC files nor %s", path, fixtureIndexFile)
+		}
+		err = f.loadWARC(files, runID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(f.responses) == 0 {
+		return nil, fmt.Errorf("replay source %s holds no responses", path)
+	}
+	logger.Info("replay archive loaded", "source", path, "runID", runID, "urls", len(f.responses))
+	return f, nil
+}
+
+// loadWARC indexes the responses of the given WARC files
+func (f *ReplayFetcher) loadWARC(files []string, runID string) error {
+	sort.Strings(files)
+	for _, file := range files {
+		err := warc.ReadResponses(file, func(response *warc.Response) error {
+			if runID != "" && response.RunID != runID {
+				return nil
+			}
+			if existing, ok := f.responses[response.URL]; ok && existing.recordedAt.After(response.Date) {
+				return nil
+			}
+			f.responses[response.URL] = &replayedResponse{
+				statusCode:  response.StatusCode,
+				contentType: response.Header.Get("Content-Type"),
+				body:        response.Body,
+				recordedAt:  response.Date,
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadFixtures reads every file listed in a fixture directory's index
+func (f *ReplayFetcher) loadFixtures(dir string) error {
+	data, err := os.ReadFile(filepath.Join(dir, fixtureIndexFile))
+	if err != nil {
+		return fmt.Errorf("failed to read fixture index: %w", err)
+	}
+	var index map[string]fixture
+	if err := json.Unmarshal(data, &index); err != nil {
+		return fmt.Errorf("failed to parse fixture index: %w", err)
+	}
+
+	for url, entry := range index {
+		body, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(entry.File)))
+		if err != nil {
+			return fmt.Errorf("failed to read fixture for %s: %w", url, err)
+		}
+		statusCode := entry.StatusCode
+		if statusCode == 0 {
+			statusCode = http.StatusOK
+		}
+		contentType := entry.ContentType
+		if contentType == "" {
+			contentType = entry.Header.Get("Content-Type")
+		}
+		f.responses[url] = &replayedResponse{statusCode: statusCode, contentType: contentType, body: body}
+	}
+	return nil
+}
+
+// Fetch implements ports.HTMLFetcher. Bodies are transcoded to UTF-8 exactly as
+// the live fetcher does, and recorded 404 and 410 answers are returned as StatusError.
+func (f *ReplayFetcher) Fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	response, ok := f.responses[url]
+	if !ok {
+		f.logger.Error("unrecorded URL requested during replay", "url", url)
+		return nil, fmt.Errorf("%w: %s", ErrNotRecorded, url)
+	}
+	if isNotFound(response.statusCode) {
+		return nil, &StatusError{URL: url, StatusCode: response.statusCode}
+	}
+
+	decoded, charsetName, err := transcodeToUTF8(response.body, response.contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	f.logger.Debug("replayed response", "url", url)
+	return newFetchedBody(decoded, ports.FetchMetadata{
+		URL:         url,
+		ContentType: response.contentType,
+		Charset:     charsetName,
+	}), nil
+}
+
+// ProxyStatuses implements ports.ProxyMonitor; replay uses no proxies
+func (f *ReplayFetcher) ProxyStatuses() []ports.ProxyStatus {
+	return []ports.ProxyStatus{}
+}
+
+// LookupURL implements ports.CacheAdmin; replay has no cache
+func (f *ReplayFetcher) LookupURL(ctx context.Context, url string) (*ports.CachedURL, bool, error) {
+	return nil, false, ports.ErrCacheUnsupported
+}
+
+// InvalidateURL implements ports.CacheAdmin; replay has no cache
+func (f *ReplayFetcher) InvalidateURL(ctx context.Context, url string) error {
+	return ports.ErrCacheUnsupported
+}
+
+// InvalidateDomain implements ports.CacheAdmin; replay has no cache
+func (f *ReplayFetcher) InvalidateDomain(ctx context.Context, domain string) (int, error) {
+	return 0, ports.ErrCacheUnsupported
+}
+
+// CacheStats implements ports.CacheAdmin; replay has no cache
+func (f *ReplayFetcher) CacheStats(ctx context.Context) (ports.CacheStats, error) {
+	return ports.CacheStats{}, ports.ErrCacheUnsupported
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Ensure ReplayFetcher implements HTMLFetcher, ProxyMonitor and CacheAdmin
+var (
+	_ ports.HTMLFetcher  = (*ReplayFetcher)(nil)
+	_ ports.ProxyMonitor = (*ReplayFetcher)(nil)
+	_ ports.CacheAdmin   = (*ReplayFetcher)(nil)
+)
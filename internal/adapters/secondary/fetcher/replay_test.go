@@ -0,0 +1,250 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"web-crawler-go/internal/adapters/secondary/warc"
+	"web-crawler-go/internal/core/ports"
+	"web-crawler-go/internal/core/services/loggerservice"
+)
+
+const (
+	replayPage    = "https://shop.example.tw/products/oolong"
+	replayListing = "https://shop.example.tw/collections/tea"
+	replayGone    = "https://shop.example.tw/products/discontinued"
+)
+
+var recordedAt = time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)
+
+// writeWARC records exchanges into a WARC file whose name starts with prefix
+func writeWARC(t *testing.T, dir, prefix string, exchanges ...ports.RecordedExchange) {
+	t.Helper()
+	writer, err := warc.NewWriter(warc.WriterConfig{Dir: dir, Prefix: prefix}, loggerservice.NewLoggerService())
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	for _, exchange := range exchanges {
+		if err := writer.Record(context.Background(), exchange); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func recordedPage(runID, url, body string, at time.Time) ports.RecordedExchange {
+	return ports.RecordedExchange{
+		RunID:          runID,
+		URL:            url,
+		StatusCode:     http.StatusOK,
+		ResponseHeader: http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+		Body:           []byte(body),
+		FetchedAt:      at,
+	}
+}
+
+// newWARCReplaySource records two crawl runs. The newer copy of replayPage is in
+// the file read first, so the latest response must win on its date, not on
+// reading order.
+func newWARCReplaySource(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeWARC(t, dir, "a",
+		recordedPage("run-2", replayPage, "<html>run 2</html>", recordedAt.Add(time.Hour)),
+		ports.RecordedExchange{RunID: "run-2", URL: replayGone, StatusCode: http.StatusGone, FetchedAt: recordedAt.Add(time.Hour)},
+	)
+	writeWARC(t, dir, "b",
+		recordedPage("run-1", replayPage, "<html>run 1</html>", recordedAt),
+		recordedPage("run-1", replayListing, "<html>listing</html>", recordedAt),
+	)
+	return dir
+}
+
+func replayedBody(t *testing.T, f *ReplayFetcher, url string) string {
+	t.Helper()
+	body, err := f.Fetch(context.Background(), url)
+	if err != nil {
+		t.Fatalf("Fetch %s: %v", url, err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+	return string(data)
+}
+
+func TestReplayFromWARC(t *testing.T) {
+	dir := newWARCReplaySource(t)
+
+	tests := []struct {
+		name  string
+		runID string
+		// want maps URLs to the body replayed for them; "" means not recorded
+		want map[string]string
+	}{
+		{
+			name:  "all runs",
+			runID: "",
+			want:  map[string]string{replayPage: "<html>run 2</html>", replayListing: "<html>listing</html>"},
+		},
+		{
+			name:  "first run",
+			runID: "run-1",
+			want:  map[string]string{replayPage: "<html>run 1</html>", replayListing: "<html>listing</html>"},
+		},
+		{
+			name:  "second run",
+			runID: "run-2",
+			want:  map[string]string{replayPage: "<html>run 2</html>", replayListing: ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewReplayFetcher(dir, tt.runID, loggerservice.NewLoggerService())
+			if err != nil {
+				t.Fatalf("NewReplayFetcher: %v", err)
+			}
+			for url, want := range tt.want {
+				if want == "" {
+					if _, err := f.Fetch(context.Background(), url); !errors.Is(err, ErrNotRecorded) {
+						t.Errorf("Fetch %s = %v, want ErrNotRecorded", url, err)
+					}
+					continue
+				}
+				if got := replayedBody(t, f, url); got != want {
+					t.Errorf("Fetch %s = %q, want %q", url, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReplayFromSingleWARCFile(t *testing.T) {
+	dir := newWARCReplaySource(t)
+	files, _ := filepath.Glob(filepath.Join(dir, "b-*.warc.gz"))
+	if len(files) != 1 {
+		t.Fatalf("found WARC files %v, want one", files)
+	}
+
+	f, err := NewReplayFetcher(files[0], "", loggerservice.NewLoggerService())
+	if err != nil {
+		t.Fatalf("NewReplayFetcher: %v", err)
+	}
+	if got := replayedBody(t, f, replayPage); got != "<html>run 1</html>" {
+		t.Errorf("Fetch = %q, want the only recorded copy", got)
+	}
+	body, err := f.Fetch(context.Background(), replayPage)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	defer body.Close()
+	metadata := body.(ports.FetchMetadataProvider).FetchMetadata()
+	if metadata.URL != replayPage || metadata.ContentType != "text/html; charset=utf-8" {
+		t.Errorf("metadata = %+v", metadata)
+	}
+}
+
+func TestReplayReturnsRecordedNotFound(t *testing.T) {
+	f, err := NewReplayFetcher(newWARCReplaySource(t), "", loggerservice.NewLoggerService())
+	if err != nil {
+		t.Fatalf("NewReplayFetcher: %v", err)
+	}
+
+	_, err = f.Fetch(context.Background(), replayGone)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusGone || statusErr.URL != replayGone {
+		t.Errorf("Fetch = %v, want a 410 StatusError", err)
+	}
+	if _, err := f.Fetch(context.Background(), "https://shop.example.tw/never-crawled"); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("Fetch of an unrecorded URL = %v, want ErrNotRecorded", err)
+	}
+}
+
+func TestReplayFromFixtures(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"pages/oolong.html": "<html>oolong</html>",
+		"api/products.json": `{"products":[]}`,
+		"missing.html":      "<html>not found</html>",
+		"unavailable.html":  "<html>maintenance</html>",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index := map[string]fixture{
+		replayPage: {File: "pages/oolong.html"},
+		"https://shop.example.tw/api/products": {
+			File:   "api/products.json",
+			Header: http.Header{"Content-Type": {"application/json"}},
+		},
+		replayGone:                         {File: "missing.html", StatusCode: http.StatusNotFound},
+		"https://shop.example.tw/checkout": {File: "unavailable.html", StatusCode: http.StatusServiceUnavailable},
+	}
+	data, _ := json.Marshal(index)
+	if err := os.WriteFile(filepath.Join(dir, fixtureIndexFile), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A run ID only selects WARC records; fixtures are always served
+	f, err := NewReplayFetcher(dir, "run-1", loggerservice.NewLoggerService())
+	if err != nil {
+		t.Fatalf("NewReplayFetcher: %v", err)
+	}
+
+	if got := replayedBody(t, f, replayPage); got != "<html>oolong</html>" {
+		t.Errorf("page = %q", got)
+	}
+	body, err := f.Fetch(context.Background(), "https://shop.example.tw/api/products")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got := body.(ports.FetchMetadataProvider).FetchMetadata().ContentType; got != "application/json" {
+		t.Errorf("content type = %q, want the one from the fixture header", got)
+	}
+	body.Close()
+
+	var statusErr *StatusError
+	if _, err := f.Fetch(context.Background(), replayGone); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Fetch of a 404 fixture = %v, want a 404 StatusError", err)
+	}
+	// Other error statuses are served as the body that was recorded
+	if got := replayedBody(t, f, "https://shop.example.tw/checkout"); got != "<html>maintenance</html>" {
+		t.Errorf("503 fixture = %q", got)
+	}
+}
+
+func TestNewReplayFetcherRejectsUnusableSources(t *testing.T) {
+	warcDir := newWARCReplaySource(t)
+
+	tests := []struct {
+		name  string
+		path  string
+		runID string
+	}{
+		{name: "missing path", path: filepath.Join(t.TempDir(), "missing")},
+		{name: "empty directory", path: t.TempDir()},
+		{name: "run that was never recorded", path: warcDir, runID: "run-3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewReplayFetcher(tt.path, tt.runID, loggerservice.NewLoggerService()); err == nil {
+				t.Errorf("NewReplayFetcher succeeded, want an error")
+			}
+		})
+	}
+}
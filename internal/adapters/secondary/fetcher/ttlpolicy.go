@@ -0,0 +1,180 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TTLRule sets the cache expiration for the responses it matches.
+// Empty conditions match everything; all set conditions must match.
+type TTLRule struct {
+	Name string `json:"name"`
+	// URLPattern is a regular expression matched against the full URL
+	URLPattern string `json:"url_pattern"`
+	// ContentType is a media type ("application/json") or wildcard ("text/*")
+	ContentType string `json:"content_type"`
+	// Provider is the provider name the crawl is using, e.g. "shopline.tw"
+	Provider string   `json:"provider"`
+	TTL      Duration `json:"ttl"`
+
+	urlPattern *regexp.Regexp
+}
+
+// TTLPolicy decides how long fetched responses stay in the cache.
+// The first matching rule wins; otherwise Cache-Control is used when enabled,
+// and Default applies to everything else.
+type TTLPolicy struct {
+	Default Duration `json:"default"`
+	// NotFoundTTL caches 404 and 410 answers so missing pages are not refetched
+	// on every crawl; zero disables negative caching
+	NotFoundTTL Duration `json:"not_found_ttl"`
+	// RespectCacheControl applies the response's max-age and no-store directives
+	// to responses no rule matched, clamped to MinTTL and MaxTTL
+	RespectCacheControl bool      `json:"respect_cache_control"`
+	MinTTL              Duration  `json:"min_ttl"`
+	MaxTTL              Duration  `json:"max_ttl"`
+	Rules               []TTLRule `json:"rules"`
+}
+
+// DefaultTTLPolicy keeps pages for a month but refreshes price and stock data daily
+func DefaultTTLPolicy() TTLPolicy {
+	return TTLPolicy{
+		Default:     Duration(730 * time.Hour),
+		NotFoundTTL: Duration(time.Hour),
+		MinTTL:      Duration(5 * time.Minute),
+		MaxTTL:      Duration(730 * time.Hour),
+		Rules: []TTLRule{
+			{Name: "product-api", URLPattern: `/api/merchants/[^/]+/products/`, TTL: Duration(6 * time.Hour)},
+			{Name: "sitemap", URLPattern: `/sitemap[^/]*\.xml$`, TTL: Duration(24 * time.Hour)},
+			{Name: "json", ContentType: "application/json", TTL: Duration(6 * time.Hour)},
+		},
+	}
+}
+
+// LoadTTLPolicy reads a JSON policy file over the default policy. Settings the file
+// leaves out keep their defaults; a "rules" list replaces the default rules.
+func LoadTTLPolicy(path string) (TTLPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return TTLPolicy{}, fmt.Errorf("failed to read TTL policy file: %w", err)
+	}
+	policy := DefaultTTLPolicy()
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return TTLPolicy{}, fmt.Errorf("failed to parse TTL policy file: %w", err)
+	}
+	return policy, nil
+}
+
+// compile prepares the rule patterns, reporting invalid ones
+func (p *TTLPolicy) compile() error {
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.URLPattern == "" {
+			continue
+		}
+		pattern, err := regexp.Compile(rule.URLPattern)
+		if err != nil {
+			return fmt.Errorf("invalid url_pattern in TTL rule %q: %w", rule.Name, err)
+		}
+		rule.urlPattern = pattern
+	}
+	return nil
+}
+
+// ttlFor returns the expiration for a response and whether it may be cached at all.
+// Error responses are never cached: a WAF block, rate limit or server error would
+// otherwise be served to every later crawl instead of being retried.
+func (p *TTLPolicy) ttlFor(url, contentType, provider string, statusCode int, header http.Header) (time.Duration, bool) {
+	if statusCode >= http.StatusBadRequest {
+		return 0, false
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	for _, rule := range p.Rules {
+		if rule.matches(url, mediaType, provider) {
+			return time.Duration(rule.TTL), rule.TTL > 0
+		}
+	}
+
+	if p.RespectCacheControl {
+		if maxAge, noStore, ok := parseCacheControl(header.Get("Cache-Control")); ok {
+			if noStore {
+				return 0, false
+			}
+			return p.clamp(maxAge), true
+		}
+	}
+
+	return time.Duration(p.Default), p.Default > 0
+}
+
+// clamp keeps a header-derived TTL within MinTTL and MaxTTL
+func (p *TTLPolicy) clamp(ttl time.Duration) time.Duration {
+	if p.MinTTL > 0 && ttl < time.Duration(p.MinTTL) {
+		return time.Duration(p.MinTTL)
+	}
+	if p.MaxTTL > 0 && ttl > time.Duration(p.MaxTTL) {
+		return time.Duration(p.MaxTTL)
+	}
+	return ttl
+}
+
+func (r *TTLRule) matches(url, mediaType, provider string) bool {
+	if r.urlPattern != nil && !r.urlPattern.MatchString(url) {
+		return false
+	}
+	if r.Provider != "" && !strings.EqualFold(r.Provider, provider) {
+		return false
+	}
+	if r.ContentType != "" {
+		if prefix, ok := strings.CutSuffix(r.ContentType, "/*"); ok {
+			if !strings.HasPrefix(mediaType, prefix+"/") {
+				return false
+			}
+		} else if !strings.EqualFold(r.ContentType, mediaType) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseCacheControl extracts max-age (preferring s-maxage) and no-store/no-cache.
+// ok is false when the header says nothing about freshness.
+func parseCacheControl(value string) (maxAge time.Duration, noStore bool, ok bool) {
+	sharedMaxAge := time.Duration(-1)
+	maxAge = -1
+	for _, directive := range strings.Split(value, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache", "private":
+			noStore = true
+		case "max-age", "s-maxage":
+			seconds, err := strconv.Atoi(strings.Trim(arg, `"`))
+			if err != nil || seconds < 0 {
+				continue
+			}
+			if strings.EqualFold(name, "s-maxage") {
+				sharedMaxAge = time.Duration(seconds) * time.Second
+			} else {
+				maxAge = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	if sharedMaxAge >= 0 {
+		maxAge = sharedMaxAge
+	}
+	if noStore {
+		return 0, true, true
+	}
+	if maxAge < 0 {
+		return 0, false, false
+	}
+	return maxAge, false, true
+}
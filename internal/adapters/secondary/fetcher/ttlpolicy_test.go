@@ -0,0 +1,129 @@
+package fetcher
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"web-crawler-go/internal/core/services/loggerservice"
+)
+
+func TestTTLPolicyTTLFor(t *testing.T) {
+	policy := DefaultTTLPolicy()
+	policy.RespectCacheControl = true
+	if err := policy.compile(); err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	const productAPI = "https://shop.example.tw/api/merchants/abc/products/123"
+	tests := []struct {
+		name          string
+		url           string
+		contentType   string
+		status        int
+		cacheControl  string
+		wantTTL       time.Duration
+		wantCacheable bool
+	}{
+		{name: "page", url: "https://shop.example.tw/", contentType: "text/html", status: http.StatusOK, wantTTL: 730 * time.Hour, wantCacheable: true},
+		{name: "product API rule", url: productAPI, contentType: "application/json", status: http.StatusOK, wantTTL: 6 * time.Hour, wantCacheable: true},
+		{name: "sitemap rule", url: "https://shop.example.tw/sitemap.xml", contentType: "application/xml", status: http.StatusOK, wantTTL: 24 * time.Hour, wantCacheable: true},
+		{name: "max-age", url: "https://shop.example.tw/", contentType: "text/html", status: http.StatusOK, cacheControl: "max-age=3600", wantTTL: time.Hour, wantCacheable: true},
+		{name: "max-age below the minimum", url: "https://shop.example.tw/", contentType: "text/html", status: http.StatusOK, cacheControl: "max-age=1", wantTTL: 5 * time.Minute, wantCacheable: true},
+		{name: "no-store", url: "https://shop.example.tw/", contentType: "text/html", status: http.StatusOK, cacheControl: "no-store"},
+		{name: "forbidden", url: "https://shop.example.tw/", contentType: "text/html", status: http.StatusForbidden},
+		{name: "rate limited", url: productAPI, contentType: "application/json", status: http.StatusTooManyRequests},
+		{name: "server error", url: "https://shop.example.tw/", contentType: "text/html", status: http.StatusBadGateway, cacheControl: "max-age=3600"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.cacheControl != "" {
+				header.Set("Cache-Control", tt.cacheControl)
+			}
+			ttl, cacheable := policy.ttlFor(tt.url, tt.contentType, "shopline.tw", tt.status, header)
+			if cacheable != tt.wantCacheable || (cacheable && ttl != tt.wantTTL) {
+				t.Errorf("ttlFor = %v, %v, want %v, %v", ttl, cacheable, tt.wantTTL, tt.wantCacheable)
+			}
+		})
+	}
+}
+
+// recordingCache is a CacheService that only remembers which keys were stored
+type recordingCache struct {
+	mu   sync.Mutex
+	sets []string
+}
+
+func (c *recordingCache) Get(ctx context.Context, key string) (io.ReadCloser, bool, error) {
+	return nil, false, nil
+}
+
+func (c *recordingCache) Set(ctx context.Context, key string, value io.ReadCloser, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sets = append(c.sets, key)
+	return nil
+}
+
+func (c *recordingCache) Delete(ctx context.Context, key string) error {
+	return nil
+}
+
+func (c *recordingCache) stored() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.sets)
+}
+
+func TestFetchDoesNotCacheErrorPages(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			io.WriteString(w, "slow down")
+			return
+		}
+		io.WriteString(w, "<html></html>")
+	}))
+	defer server.Close()
+
+	cache := &recordingCache{}
+	f, err := NewHTTPFetcher(cache, nil, DefaultConfig(), loggerservice.NewLoggerService())
+	if err != nil {
+		t.Fatalf("NewHTTPFetcher: %v", err)
+	}
+
+	fetch := func() string {
+		t.Helper()
+		body, err := f.Fetch(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("Fetch: %v", err)
+		}
+		defer body.Close()
+		data, _ := io.ReadAll(body)
+		return string(data)
+	}
+
+	if got := fetch(); got != "slow down" {
+		t.Errorf("first fetch = %q, want the rate limit page", got)
+	}
+	if got := fetch(); got != "<html></html>" {
+		t.Errorf("second fetch = %q, want the page fetched again", got)
+	}
+
+	// Only the successful response is stored, in the background
+	deadline := time.Now().Add(2 * time.Second)
+	for cache.stored() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := cache.stored(); got != 1 {
+		t.Errorf("stored %d responses, want only the successful one", got)
+	}
+}
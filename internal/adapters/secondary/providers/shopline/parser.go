@@ -79,7 +79,7 @@ func (p *Parser) ProcessProducts(ctx context.Context, url string) ([]*domain.Pro
 // Parse implements the ProductProvider interface
 func (p *Parser) Parse(ctx context.Context, html io.Reader) (*domain.Product, error) {
 	// Read the HTML content
-	htmlBytes, err := io.ReadAll(html)
+	htmlBytes, err := ports.ReadAll(html)
 	if err != nil {
 		p.logger.Error("failed to read HTML", "error", err)
 		return nil, fmt.Errorf("failed to read HTML: %w", err)
@@ -114,6 +114,10 @@ func (p *Parser) Parse(ctx context.Context, html io.Reader) (*domain.Product, er
 func (p *Parser) parseProductURLsFromSitemap(body io.Reader) ([]string, error) {
 	var sitemap Sitemap
 	decoder := xml.NewDecoder(body)
+	// The fetcher already transcodes bodies to UTF-8, so ignore any declared encoding
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
 
 	if err := decoder.Decode(&sitemap); err != nil {
 		p.logger.Error("failed to decode XML", "error", err)
@@ -158,11 +162,16 @@ func (p *Parser) fetchAndParseProduct(ctx context.Context, productURL string) (*
 	}
 
 	// Use the parseProductResponse method
-	return p.parseProductResponse(productData)
+	product, err := p.parseProductResponse(productData)
+	if err != nil {
+		return nil, err
+	}
+	product.SourceURL = productURL
+	return product, nil
 }
 
 func (p *Parser) parseMerchantIDAndProductID(htmlBody io.ReadCloser) (*string, *string, error) {
-	bodyBytes, err := io.ReadAll(htmlBody)
+	bodyBytes, err := ports.ReadAll(htmlBody)
 	if err != nil {
 		p.logger.Error("failed to read HTML body", "error", err)
 		return nil, nil, fmt.Errorf("failed to read HTML body: %w", err)
@@ -245,17 +254,28 @@ func (p *Parser) parseProductResponse(apiResponse *ProductResponse) (*domain.Pro
 		Price:           apiResponse.Data.Price.Cents,
 		PriceDiscounted: apiResponse.Data.PriceSale.Cents,
 		Description:     apiResponse.Data.DescriptionTranslations["zh-hant"],
-		Status:          "active",
+		Status:          domain.StatusActive,
 	}
 
 	if apiResponse.Data.Quantity < 1 {
-		productShopLine.Status = "outOfStock"
+		productShopLine.Status = domain.StatusOutOfStock
 	}
 
 	for _, media := range apiResponse.Data.Media {
 		productShopLine.ImagesURL = append(productShopLine.ImagesURL, media.Images.Original.URL)
 	}
 
+	for _, variation := range apiResponse.Data.Variations {
+		sku, _ := variation.SKU.(string)
+		productShopLine.Variants = append(productShopLine.Variants, domain.ProductVariant{
+			Options:         variation.FieldsTranslations["zh-hant"],
+			SKU:             sku,
+			Price:           variation.Price.Cents,
+			PriceDiscounted: variation.PriceSale.Cents,
+			Quantity:        variation.Quantity,
+		})
+	}
+
 	return productShopLine, nil
 }
 
@@ -268,7 +288,7 @@ func (p *Parser) fetchProductData(ctx context.Context, hostname string, merchant
 	}
 	defer fetchResponse.Close()
 
-	bodyBytes, err := io.ReadAll(fetchResponse)
+	bodyBytes, err := ports.ReadAll(fetchResponse)
 	if err != nil {
 		return nil, err
 	}
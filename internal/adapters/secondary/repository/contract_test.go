@@ -0,0 +1,648 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"web-crawler-go/internal/core/domain"
+	"web-crawler-go/internal/core/ports"
+	"web-crawler-go/internal/core/services/loggerservice"
+)
+
+// contractDomain is the store every contract test writes to. Databases shared
+// with other data are cleaned of it before and after each test.
+const contractDomain = "contract.example.tw"
+
+// contractRepository opens a repository for one test and registers its cleanup
+type contractRepository func(t *testing.T) ports.ProductRepository
+
+// contractRepositories are the adapters the contract runs against. PostgreSQL
+// and MongoDB are skipped unless TEST_POSTGRES_DSN or TEST_MONGODB_URI is set;
+// point them at disposable databases.
+var contractRepositories = []struct {
+	name string
+	open contractRepository
+}{
+	{name: "memory", open: openMemoryRepository},
+	{name: "sqlite", open: openSQLiteRepository},
+	{name: "postgres", open: openPostgresRepository},
+	{name: "mongodb", open: openMongoDBRepository},
+}
+
+func openMemoryRepository(t *testing.T) ports.ProductRepository {
+	return NewMemoryRepository(loggerservice.NewLoggerService())
+}
+
+func openSQLiteRepository(t *testing.T) ports.ProductRepository {
+	ctx := context.Background()
+	repo, err := NewSQLiteRepository(ctx, filepath.Join(t.TempDir(), "products.db"), loggerservice.NewLoggerService())
+	if err != nil {
+		t.Fatalf("NewSQLiteRepository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close(ctx) })
+	return repo
+}
+
+func openPostgresRepository(t *testing.T) ports.ProductRepository {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+	ctx := context.Background()
+	repo, err := NewPostgresRepository(ctx, dsn, loggerservice.NewLoggerService())
+	if err != nil {
+		t.Fatalf("NewPostgresRepository: %v", err)
+	}
+	clean := func() {
+		for _, table := range []string{"products", "crawls"} {
+			if _, err := repo.db.ExecContext(ctx, "DELETE FROM "+table+" WHERE domain = $1", contractDomain); err != nil {
+				t.Fatalf("failed to clean %s: %v", table, err)
+			}
+		}
+	}
+	clean()
+	t.Cleanup(func() {
+		clean()
+		repo.Close(ctx)
+	})
+	return repo
+}
+
+func openMongoDBRepository(t *testing.T) ports.ProductRepository {
+	uri := os.Getenv("TEST_MONGODB_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGODB_URI not set")
+	}
+	ctx := context.Background()
+	collection := fmt.Sprintf("contract_%d", time.Now().UnixNano())
+	repo, err := NewMongoDBRepository(ctx, uri, "web_crawler_test", collection, loggerservice.NewLoggerService())
+	if err != nil {
+		t.Fatalf("NewMongoDBRepository: %v", err)
+	}
+	t.Cleanup(func() {
+		repo.collection.Drop(ctx)
+		repo.crawls.Drop(ctx)
+		repo.Close(ctx)
+	})
+	return repo
+}
+
+// runContract runs test against every available repository
+func runContract(t *testing.T, test func(t *testing.T, repo ports.ProductRepository)) {
+	for _, backend := range contractRepositories {
+		t.Run(backend.name, func(t *testing.T) {
+			test(t, backend.open(t))
+		})
+	}
+}
+
+// contractProducts is the catalogue the ordering, count and filter tests query.
+// Prices and names are distinct so every sort order is total.
+func contractProducts() []*domain.Product {
+	return []*domain.Product{
+		{Domain: contractDomain, Name: "Green tea", Price: 300, PriceDiscounted: 250, Tags: []string{"tea"}, ImagesURL: []string{"https://img.example.tw/green-1.jpg", "https://img.example.tw/green-2.jpg"}, Status: domain.StatusActive, Provider: "shopline.tw", CrawlRunID: "run-1"},
+		{Domain: contractDomain, Name: "Oolong tea", Price: 450, Tags: []string{"tea"}, Status: domain.StatusActive, Provider: "shopline.tw", CrawlRunID: "run-1"},
+		{Domain: contractDomain, Name: "Tea cup", Price: 800, PriceDiscounted: 600, Tags: []string{"teaware"}, Status: domain.StatusOutOfStock, Provider: "shopline.tw", CrawlRunID: "run-2"},
+		{Domain: contractDomain, Name: "Jasmine tea", Description: "Scented with jasmine flowers", Price: 350, Tags: []string{"tea", "scented"}, Status: domain.StatusActive, Provider: "shopify", CrawlRunID: "run-2"},
+		{Domain: contractDomain, Name: "Kettle", Price: 1200, Tags: []string{"teaware"}, ImagesURL: []string{"https://img.example.tw/kettle.jpg"}, Status: domain.StatusActive, Provider: "shopify", CrawlRunID: "run-2"},
+	}
+}
+
+func seedContractProducts(t *testing.T, repo ports.ProductRepository) {
+	saved, err := repo.UpsertProducts(context.Background(), contractProducts())
+	if err != nil {
+		t.Fatalf("UpsertProducts: %v", err)
+	}
+	if saved != len(contractProducts()) {
+		t.Fatalf("saved %d products, want %d", saved, len(contractProducts()))
+	}
+}
+
+func productNames(products []*domain.Product) []string {
+	names := make([]string, len(products))
+	for i, product := range products {
+		names[i] = product.Name
+	}
+	return names
+}
+
+func TestContractUpsertIdentity(t *testing.T) {
+	runContract(t, func(t *testing.T, repo ports.ProductRepository) {
+		ctx := context.Background()
+		product := &domain.Product{
+			Domain: contractDomain, Name: "Green tea", Price: 300, Tags: []string{"tea"}, Status: domain.StatusActive,
+			ImagesURL: []string{"https://img.example.tw/green.jpg"},
+			Variants: []domain.ProductVariant{
+				{Options: []string{"50g"}, SKU: "GT-50", Price: 300, Quantity: 4},
+				{Options: []string{"100g"}, SKU: "GT-100", Price: 550, PriceDiscounted: 500},
+			},
+			Provider: "shopline.tw", CrawlRunID: "run-1",
+		}
+		if err := repo.UpsertProduct(ctx, product); err != nil {
+			t.Fatalf("UpsertProduct: %v", err)
+		}
+		first := getOnlyProduct(t, repo)
+		if !slices.Equal(first.ImagesURL, product.ImagesURL) || !slices.Equal(first.Tags, product.Tags) {
+			t.Errorf("images %v and tags %v, want %v and %v", first.ImagesURL, first.Tags, product.ImagesURL, product.Tags)
+		}
+		if len(first.Variants) != len(product.Variants) {
+			t.Fatalf("variants = %+v, want %+v", first.Variants, product.Variants)
+		}
+		for i, variant := range first.Variants {
+			want := product.Variants[i]
+			if !slices.Equal(variant.Options, want.Options) || variant.SKU != want.SKU || variant.Price != want.Price ||
+				variant.PriceDiscounted != want.PriceDiscounted || variant.Quantity != want.Quantity {
+				t.Errorf("variant %d = %+v, want %+v", i, variant, want)
+			}
+		}
+
+		// Saving the same domain and name again updates the product in place
+		updated := *product
+		updated.Price = 280
+		updated.Status = domain.StatusOutOfStock
+		updated.Variants = nil
+		updated.CrawlRunID = "run-2"
+		if _, err := repo.UpsertProducts(ctx, []*domain.Product{&updated}); err != nil {
+			t.Fatalf("UpsertProducts: %v", err)
+		}
+		second := getOnlyProduct(t, repo)
+		if second.ID != first.ID {
+			t.Errorf("ID changed from %q to %q", first.ID, second.ID)
+		}
+		if !second.CreatedAt.Equal(first.CreatedAt) {
+			t.Errorf("CreatedAt changed from %v to %v", first.CreatedAt, second.CreatedAt)
+		}
+		if second.UpdatedAt.Before(first.UpdatedAt) {
+			t.Errorf("UpdatedAt went back from %v to %v", first.UpdatedAt, second.UpdatedAt)
+		}
+		if second.Price != 280 || second.Status != domain.StatusOutOfStock || len(second.Variants) != 0 || second.CrawlRunID != "run-2" {
+			t.Errorf("product = %+v, want the updated price, status, variants and run", second)
+		}
+
+		byID, err := repo.GetProduct(ctx, first.ID)
+		if err != nil {
+			t.Fatalf("GetProduct: %v", err)
+		}
+		if byID.Name != product.Name || byID.Domain != contractDomain {
+			t.Errorf("GetProduct = %s/%s, want %s/%s", byID.Domain, byID.Name, contractDomain, product.Name)
+		}
+
+		// The same name in another store is another product
+		other := *product
+		other.Domain = "other." + contractDomain
+		if err := repo.UpsertProduct(ctx, &other); err != nil {
+			t.Fatalf("UpsertProduct: %v", err)
+		}
+		t.Cleanup(func() { deleteDomain(t, repo, other.Domain) })
+		if total, err := repo.GetTotalProducts(ctx, ports.ProductQuery{Domain: contractDomain}); err != nil || total != 1 {
+			t.Errorf("GetTotalProducts = %d, %v, want 1", total, err)
+		}
+	})
+}
+
+// getOnlyProduct returns the single product of the contract domain
+func getOnlyProduct(t *testing.T, repo ports.ProductRepository) *domain.Product {
+	t.Helper()
+	ctx := context.Background()
+	page, err := repo.GetProducts(ctx, ports.ProductQuery{Domain: contractDomain, Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatalf("GetProducts: %v", err)
+	}
+	if len(page.Products) != 1 {
+		t.Fatalf("products = %v, want one", productNames(page.Products))
+	}
+	total, err := repo.GetTotalProducts(ctx, ports.ProductQuery{Domain: contractDomain})
+	if err != nil || total != 1 {
+		t.Fatalf("GetTotalProducts = %d, %v, want 1", total, err)
+	}
+	product := page.Products[0]
+	if product.ID == "" || product.CreatedAt.IsZero() || product.UpdatedAt.IsZero() {
+		t.Fatalf("product %q has ID %q, created %v and updated %v, want all set", product.Name, product.ID, product.CreatedAt, product.UpdatedAt)
+	}
+	return product
+}
+
+// deleteDomain removes the products and crawls of a domain other than
+// contractDomain written by a test from the shared databases
+func deleteDomain(t *testing.T, repo ports.ProductRepository, domainName string) {
+	ctx := context.Background()
+	switch r := repo.(type) {
+	case *MemoryRepository, *SQLiteRepository:
+		// Every test gets a repository of its own
+	case *PostgresRepository:
+		for _, table := range []string{"products", "crawls"} {
+			if _, err := r.db.ExecContext(ctx, "DELETE FROM "+table+" WHERE domain = $1", domainName); err != nil {
+				t.Errorf("failed to clean %s from %s: %v", domainName, table, err)
+			}
+		}
+	case *MongoDBRepository:
+		filter := bson.M{"domain": domainName}
+		if _, err := r.collection.DeleteMany(ctx, filter); err != nil {
+			t.Errorf("failed to clean %s from products: %v", domainName, err)
+		}
+		if _, err := r.crawls.DeleteMany(ctx, filter); err != nil {
+			t.Errorf("failed to clean %s from crawls: %v", domainName, err)
+		}
+	default:
+		t.Fatalf("deleteDomain does not know how to clean %T", repo)
+	}
+}
+
+func TestContractGetProductNotFound(t *testing.T) {
+	runContract(t, func(t *testing.T, repo ports.ProductRepository) {
+		seedContractProducts(t, repo)
+		// A valid ID in every backend's format that no product has
+		for _, id := range []string{"999999", "000000000000000000000000"} {
+			if _, err := repo.GetProduct(context.Background(), id); !errors.Is(err, ports.ErrProductNotFound) {
+				t.Errorf("GetProduct(%q) error = %v, want ErrProductNotFound", id, err)
+			}
+		}
+	})
+}
+
+func TestContractPageOrdering(t *testing.T) {
+	tests := []struct {
+		name       string
+		sort       ports.ProductSortKey
+		descending bool
+		want       []string
+	}{
+		{name: "inserted", sort: ports.SortInserted, want: []string{"Green tea", "Oolong tea", "Tea cup", "Jasmine tea", "Kettle"}},
+		{name: "price", sort: ports.SortPrice, want: []string{"Green tea", "Jasmine tea", "Oolong tea", "Tea cup", "Kettle"}},
+		{name: "price descending", sort: ports.SortPrice, descending: true, want: []string{"Kettle", "Tea cup", "Oolong tea", "Jasmine tea", "Green tea"}},
+		{name: "name", sort: ports.SortName, want: []string{"Green tea", "Jasmine tea", "Kettle", "Oolong tea", "Tea cup"}},
+		{name: "name descending", sort: ports.SortName, descending: true, want: []string{"Tea cup", "Oolong tea", "Kettle", "Jasmine tea", "Green tea"}},
+		{name: "discount descending", sort: ports.SortDiscount, descending: true, want: []string{"Tea cup", "Green tea", "Kettle", "Jasmine tea", "Oolong tea"}},
+	}
+
+	runContract(t, func(t *testing.T, repo ports.ProductRepository) {
+		seedContractProducts(t, repo)
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				checkPaging(t, repo, ports.ProductQuery{Domain: contractDomain, Sort: tt.sort, Descending: tt.descending}, tt.want)
+			})
+		}
+	})
+}
+
+// checkPaging reads the query two products at a time, by page number and by
+// cursor, and checks both return want in order
+func checkPaging(t *testing.T, repo ports.ProductRepository, query ports.ProductQuery, want []string) {
+	t.Helper()
+	ctx := context.Background()
+	query.PageSize = 2
+
+	// Page numbers
+	var paged []string
+	for page := 1; page <= (len(want)+1)/2; page++ {
+		query.Page = page
+		result, err := repo.GetProducts(ctx, query)
+		if err != nil {
+			t.Fatalf("GetProducts page %d: %v", page, err)
+		}
+		paged = append(paged, productNames(result.Products)...)
+	}
+	if !slices.Equal(paged, want) {
+		t.Errorf("pages = %v, want %v", paged, want)
+	}
+
+	// Cursors
+	query.Page = 0
+	var cursored []string
+	for range len(want) {
+		result, err := repo.GetProducts(ctx, query)
+		if err != nil {
+			t.Fatalf("GetProducts after cursor %q: %v", query.Cursor, err)
+		}
+		cursored = append(cursored, productNames(result.Products)...)
+		if result.NextCursor == "" {
+			break
+		}
+		query.Cursor = result.NextCursor
+	}
+	if !slices.Equal(cursored, want) {
+		t.Errorf("cursor pages = %v, want %v", cursored, want)
+	}
+}
+
+func TestContractUpdatedAtPaging(t *testing.T) {
+	runContract(t, func(t *testing.T, repo ports.ProductRepository) {
+		ctx := context.Background()
+		// One save at a time, far enough apart for SQLite's millisecond timestamps
+		// to differ, then Green tea again so updates and inserts interleave
+		products := contractProducts()
+		for _, product := range append(products, products[0]) {
+			time.Sleep(5 * time.Millisecond)
+			if err := repo.UpsertProduct(ctx, product); err != nil {
+				t.Fatalf("UpsertProduct: %v", err)
+			}
+		}
+
+		want := []string{"Oolong tea", "Tea cup", "Jasmine tea", "Kettle", "Green tea"}
+		t.Run("ascending", func(t *testing.T) {
+			checkPaging(t, repo, ports.ProductQuery{Domain: contractDomain, Sort: ports.SortUpdatedAt}, want)
+		})
+		t.Run("descending", func(t *testing.T) {
+			reversed := slices.Clone(want)
+			slices.Reverse(reversed)
+			checkPaging(t, repo, ports.ProductQuery{Domain: contractDomain, Sort: ports.SortUpdatedAt, Descending: true}, reversed)
+		})
+	})
+}
+
+func TestContractCountsAndFilters(t *testing.T) {
+	minPrice, maxPrice := 400, 800
+	tests := []struct {
+		name  string
+		query ports.ProductQuery
+		want  []string
+	}{
+		{name: "all", query: ports.ProductQuery{}, want: []string{"Green tea", "Jasmine tea", "Kettle", "Oolong tea", "Tea cup"}},
+		{name: "other domain", query: ports.ProductQuery{Domain: "missing.example.tw"}, want: nil},
+		{name: "status", query: ports.ProductQuery{Status: domain.StatusOutOfStock}, want: []string{"Tea cup"}},
+		{name: "tag", query: ports.ProductQuery{Tag: "teaware"}, want: []string{"Kettle", "Tea cup"}},
+		{name: "price range", query: ports.ProductQuery{MinPrice: &minPrice, MaxPrice: &maxPrice}, want: []string{"Oolong tea", "Tea cup"}},
+		{name: "on sale", query: ports.ProductQuery{OnSale: true}, want: []string{"Green tea", "Tea cup"}},
+		{name: "provider", query: ports.ProductQuery{Provider: "shopify"}, want: []string{"Jasmine tea", "Kettle"}},
+		{name: "crawl run", query: ports.ProductQuery{CrawlRunID: "run-1"}, want: []string{"Green tea", "Oolong tea"}},
+		{name: "search", query: ports.ProductQuery{Search: "jasmine"}, want: []string{"Jasmine tea"}},
+		{name: "combined", query: ports.ProductQuery{Tag: "tea", Provider: "shopline.tw", OnSale: true}, want: []string{"Green tea"}},
+		{name: "updated since", query: ports.ProductQuery{UpdatedSince: time.Now().Add(-time.Hour)}, want: []string{"Green tea", "Jasmine tea", "Kettle", "Oolong tea", "Tea cup"}},
+		{name: "created in the future", query: ports.ProductQuery{CreatedSince: time.Now().Add(time.Hour)}, want: nil},
+	}
+
+	runContract(t, func(t *testing.T, repo ports.ProductRepository) {
+		seedContractProducts(t, repo)
+		ctx := context.Background()
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				query := tt.query
+				if query.Domain == "" {
+					query.Domain = contractDomain
+				}
+				query.Sort = ports.SortName
+				query.Page = 1
+				query.PageSize = 10
+
+				page, err := repo.GetProducts(ctx, query)
+				if err != nil {
+					t.Fatalf("GetProducts: %v", err)
+				}
+				if got := productNames(page.Products); !slices.Equal(got, tt.want) {
+					t.Errorf("products = %v, want %v", got, tt.want)
+				}
+				total, err := repo.GetTotalProducts(ctx, query)
+				if err != nil {
+					t.Fatalf("GetTotalProducts: %v", err)
+				}
+				if total != len(tt.want) {
+					t.Errorf("GetTotalProducts = %d, want %d", total, len(tt.want))
+				}
+			})
+		}
+	})
+}
+
+// Domains the catalogue tests write next to contractDomain
+const (
+	crawledOnlyDomain = "crawled-only." + contractDomain
+	uncrawledDomain   = "uncrawled." + contractDomain
+)
+
+// contractCrawlTime is when the first recorded crawl started
+var contractCrawlTime = time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)
+
+// contractDomainNames keeps the domains written by the catalogue tests, in the
+// listed order; shared databases may hold others
+func contractDomainNames(domains []*ports.DomainSummary) []string {
+	var names []string
+	for _, summary := range domains {
+		if summary.Domain == contractDomain || strings.HasSuffix(summary.Domain, "."+contractDomain) {
+			names = append(names, summary.Domain)
+		}
+	}
+	return names
+}
+
+// seedContractCatalogue stores three domains: contractDomain with the contract
+// products and two crawls, one crawled without products and one with a product
+// but no crawl. It returns the latest crawl of each crawled domain.
+func seedContractCatalogue(t *testing.T, repo ports.ProductRepository) map[string]ports.CrawlRecord {
+	t.Helper()
+	ctx := context.Background()
+	for _, name := range []string{crawledOnlyDomain, uncrawledDomain} {
+		deleteDomain(t, repo, name)
+		t.Cleanup(func() { deleteDomain(t, repo, name) })
+	}
+
+	seedContractProducts(t, repo)
+	teapot := &domain.Product{Domain: uncrawledDomain, Name: "Teapot", Price: 900, Status: domain.StatusActive, Provider: "shopify"}
+	if err := repo.UpsertProduct(ctx, teapot); err != nil {
+		t.Fatalf("UpsertProduct: %v", err)
+	}
+
+	first := ports.CrawlRecord{
+		RunID: "run-1", Domain: contractDomain, Provider: "shopline.tw", Status: ports.CrawlCompleted, ProductsCount: 5,
+		StartedAt: contractCrawlTime, FinishedAt: contractCrawlTime.Add(2 * time.Minute),
+	}
+	latest := ports.CrawlRecord{
+		RunID: "run-2", Domain: contractDomain, Provider: "shopline.tw", Status: ports.CrawlFailed, Error: "sitemap unavailable",
+		StartedAt: contractCrawlTime.Add(time.Hour), FinishedAt: contractCrawlTime.Add(time.Hour + 30*time.Second),
+	}
+	crawledOnly := ports.CrawlRecord{
+		RunID: "run-3", Domain: crawledOnlyDomain, Provider: "shopify", Status: ports.CrawlFailed, Error: "no products found",
+		StartedAt: contractCrawlTime.Add(2 * time.Hour), FinishedAt: contractCrawlTime.Add(2*time.Hour + time.Second),
+	}
+	// The latest crawl is the one that finished last, not the last recorded
+	for _, crawl := range []ports.CrawlRecord{latest, crawledOnly, first} {
+		if err := repo.RecordCrawl(ctx, crawl); err != nil {
+			t.Fatalf("RecordCrawl %s: %v", crawl.RunID, err)
+		}
+	}
+	return map[string]ports.CrawlRecord{contractDomain: latest, crawledOnlyDomain: crawledOnly}
+}
+
+func TestContractRecordCrawlAndListDomains(t *testing.T) {
+	tests := []struct {
+		domain           string
+		wantProducts     int
+		wantProvider     string
+		wantStatusCounts map[string]int
+	}{
+		{
+			domain:           contractDomain,
+			wantProducts:     5,
+			wantProvider:     "shopline.tw",
+			wantStatusCounts: map[string]int{domain.StatusActive: 4, domain.StatusOutOfStock: 1},
+		},
+		// Without products the provider comes from the crawl
+		{domain: crawledOnlyDomain, wantProvider: "shopify", wantStatusCounts: map[string]int{}},
+		{domain: uncrawledDomain, wantProducts: 1, wantProvider: "shopify", wantStatusCounts: map[string]int{domain.StatusActive: 1}},
+	}
+
+	runContract(t, func(t *testing.T, repo ports.ProductRepository) {
+		lastCrawls := seedContractCatalogue(t, repo)
+		page, err := repo.ListDomains(context.Background(), ports.DomainQuery{Sort: ports.SortDomainName, Page: 1, PageSize: 1000})
+		if err != nil {
+			t.Fatalf("ListDomains: %v", err)
+		}
+		summaries := make(map[string]*ports.DomainSummary)
+		for _, summary := range page.Domains {
+			summaries[summary.Domain] = summary
+		}
+		if page.TotalItems != len(page.Domains) {
+			t.Errorf("TotalItems = %d, want the %d domains listed", page.TotalItems, len(page.Domains))
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.domain, func(t *testing.T) {
+				summary, ok := summaries[tt.domain]
+				if !ok {
+					t.Fatalf("%s not listed", tt.domain)
+				}
+				if summary.ProductCount != tt.wantProducts || summary.Provider != tt.wantProvider {
+					t.Errorf("%d products from %q, want %d from %q", summary.ProductCount, summary.Provider, tt.wantProducts, tt.wantProvider)
+				}
+				if !maps.Equal(summary.StatusCounts, tt.wantStatusCounts) {
+					t.Errorf("status counts = %v, want %v", summary.StatusCounts, tt.wantStatusCounts)
+				}
+
+				want, crawled := lastCrawls[tt.domain]
+				if !crawled {
+					if summary.LastCrawl != nil {
+						t.Errorf("last crawl = %+v, want none", summary.LastCrawl)
+					}
+					return
+				}
+				got := summary.LastCrawl
+				if got == nil {
+					t.Fatalf("no last crawl, want %s", want.RunID)
+				}
+				if got.RunID != want.RunID || got.Domain != want.Domain || got.Provider != want.Provider || got.Status != want.Status ||
+					got.ProductsCount != want.ProductsCount || got.Error != want.Error {
+					t.Errorf("last crawl = %+v, want %+v", got, want)
+				}
+				if !got.StartedAt.Equal(want.StartedAt) || !got.FinishedAt.Equal(want.FinishedAt) {
+					t.Errorf("last crawl ran %v to %v, want %v to %v", got.StartedAt, got.FinishedAt, want.StartedAt, want.FinishedAt)
+				}
+			})
+		}
+	})
+}
+
+func TestContractDomainOrdering(t *testing.T) {
+	tests := []struct {
+		name       string
+		sort       ports.DomainSortKey
+		descending bool
+		want       []string
+	}{
+		{name: "domain", sort: ports.SortDomainName, want: []string{contractDomain, crawledOnlyDomain, uncrawledDomain}},
+		{name: "domain descending", sort: ports.SortDomainName, descending: true, want: []string{uncrawledDomain, crawledOnlyDomain, contractDomain}},
+		{name: "product count", sort: ports.SortProductCount, want: []string{crawledOnlyDomain, uncrawledDomain, contractDomain}},
+		{name: "product count descending", sort: ports.SortProductCount, descending: true, want: []string{contractDomain, uncrawledDomain, crawledOnlyDomain}},
+		// Never crawled domains last in either direction
+		{name: "last crawled", sort: ports.SortLastCrawledAt, want: []string{contractDomain, crawledOnlyDomain, uncrawledDomain}},
+		{name: "last crawled descending", sort: ports.SortLastCrawledAt, descending: true, want: []string{crawledOnlyDomain, contractDomain, uncrawledDomain}},
+	}
+
+	runContract(t, func(t *testing.T, repo ports.ProductRepository) {
+		seedContractCatalogue(t, repo)
+		ctx := context.Background()
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				query := ports.DomainQuery{Sort: tt.sort, Descending: tt.descending, Page: 1, PageSize: 1000}
+				all, err := repo.ListDomains(ctx, query)
+				if err != nil {
+					t.Fatalf("ListDomains: %v", err)
+				}
+				if got := contractDomainNames(all.Domains); !slices.Equal(got, tt.want) {
+					t.Errorf("domains = %v, want %v", got, tt.want)
+				}
+
+				// One domain a page lists the same order
+				query.PageSize = 1
+				var paged []*ports.DomainSummary
+				for query.Page = 1; query.Page <= all.TotalItems; query.Page++ {
+					page, err := repo.ListDomains(ctx, query)
+					if err != nil {
+						t.Fatalf("ListDomains page %d: %v", query.Page, err)
+					}
+					if len(page.Domains) != 1 || page.TotalItems != all.TotalItems {
+						t.Fatalf("page %d = %d domains of %d, want 1 of %d", query.Page, len(page.Domains), page.TotalItems, all.TotalItems)
+					}
+					paged = append(paged, page.Domains...)
+				}
+				if got := contractDomainNames(paged); !slices.Equal(got, tt.want) {
+					t.Errorf("pages = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	})
+}
+
+func TestContractGetDomainStats(t *testing.T) {
+	runContract(t, func(t *testing.T, repo ports.ProductRepository) {
+		seedContractProducts(t, repo)
+		ctx := context.Background()
+		stats, err := repo.GetDomainStats(ctx, contractDomain)
+		if err != nil {
+			t.Fatalf("GetDomainStats: %v", err)
+		}
+
+		// Prices 300, 350, 450, 800 and 1200 in buckets 91 wide
+		wantBuckets := ports.NewPriceBuckets(300, 1200)
+		for i, count := range map[int]int{0: 2, 1: 1, 5: 1, 9: 1} {
+			wantBuckets[i].Count = count
+		}
+		// Green tea is 1/6 off and the tea cup 1/4
+		wantDiscount := (100.0/6 + 25) / 2
+
+		if stats.Domain != contractDomain || stats.ProductCount != 5 || stats.InStockCount != 4 || stats.OnSaleCount != 2 {
+			t.Errorf("stats of %s = %d products, %d in stock, %d on sale, want %s with 5, 4 and 2",
+				stats.Domain, stats.ProductCount, stats.InStockCount, stats.OnSaleCount, contractDomain)
+		}
+		for _, ratio := range []struct {
+			name      string
+			got, want float64
+		}{
+			{name: "in stock ratio", got: stats.InStockRatio, want: 0.8},
+			{name: "average discount", got: stats.AverageDiscount, want: wantDiscount},
+			{name: "images per product", got: stats.ImagesPerProduct, want: 0.6},
+			{name: "median price", got: stats.Price.Median, want: 450},
+		} {
+			if math.Abs(ratio.got-ratio.want) > 1e-9 {
+				t.Errorf("%s = %v, want %v", ratio.name, ratio.got, ratio.want)
+			}
+		}
+		if stats.Price.Min != 300 || stats.Price.Max != 1200 {
+			t.Errorf("prices from %d to %d, want 300 to 1200", stats.Price.Min, stats.Price.Max)
+		}
+		if !slices.Equal(stats.Price.Buckets, wantBuckets) {
+			t.Errorf("buckets = %v, want %v", stats.Price.Buckets, wantBuckets)
+		}
+		wantTags := []ports.TagCount{{Tag: "tea", Count: 3}, {Tag: "teaware", Count: 2}, {Tag: "scented", Count: 1}}
+		if !slices.Equal(stats.TopTags, wantTags) {
+			t.Errorf("top tags = %v, want %v", stats.TopTags, wantTags)
+		}
+
+		// The service turns an empty summary into ErrDomainNotFound
+		empty, err := repo.GetDomainStats(ctx, "missing.example.tw")
+		if err != nil {
+			t.Fatalf("GetDomainStats of a missing domain: %v", err)
+		}
+		if empty.ProductCount != 0 || len(empty.TopTags) != 0 || len(empty.Price.Buckets) != 0 {
+			t.Errorf("stats of a missing domain = %+v, want empty", empty)
+		}
+	})
+}
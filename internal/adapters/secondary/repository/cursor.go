@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"web-crawler-go/internal/core/ports"
+)
+
+// productCursor marks where a page ended: the sort value and ID of its last
+// product. Clients get it as an opaque token, which is only accepted back for the
+// same sort order. Keyset conditions on the pair replace skipping, so deep pages
+// stay cheap and concurrent upserts cannot shift products across page boundaries.
+type productCursor struct {
+	Sort       ports.ProductSortKey `json:"s,omitempty"`
+	Descending bool                 `json:"d,omitempty"`
+	// Value is the last product's sort value, absent for insertion order
+	Value json.RawMessage `json:"v,omitempty"`
+	// ID is the last product's _id in hex, or its row or sequence number
+	ID string `json:"id"`
+}
+
+// encodeProductCursor returns the token continuing query after the product with
+// the given sort value and ID
+func encodeProductCursor(query ports.ProductQuery, value any, id string) (string, error) {
+	cursor := productCursor{Sort: query.Sort, Descending: query.Descending, ID: id}
+	if query.Sort != ports.SortInserted {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode cursor: %w", err)
+		}
+		cursor.Value = encoded
+	}
+
+	token, err := json.Marshal(cursor)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// decodeProductCursor parses the query's cursor, returning nil when there is none.
+// Errors wrap ports.ErrInvalidCursor.
+func decodeProductCursor(query ports.ProductQuery) (*productCursor, error) {
+	if query.Cursor == "" {
+		return nil, nil
+	}
+
+	token, err := base64.RawURLEncoding.DecodeString(query.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ports.ErrInvalidCursor, err)
+	}
+	var cursor productCursor
+	if err := json.Unmarshal(token, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: %v", ports.ErrInvalidCursor, err)
+	}
+	if cursor.ID == "" {
+		return nil, fmt.Errorf("%w: missing ID", ports.ErrInvalidCursor)
+	}
+	if cursor.Sort != query.Sort || cursor.Descending != query.Descending {
+		return nil, fmt.Errorf("%w: issued for a different sort order", ports.ErrInvalidCursor)
+	}
+	return &cursor, nil
+}
+
+// sortValue decodes Value into the type of the sort key: int for price, string
+// for name, time.Time for updated_at and float64 for discount. It is nil for
+// insertion order.
+func (c *productCursor) sortValue() (any, error) {
+	var target any
+	switch c.Sort {
+	case ports.SortPrice:
+		target = new(int)
+	case ports.SortName:
+		target = new(string)
+	case ports.SortUpdatedAt:
+		target = new(time.Time)
+	case ports.SortDiscount:
+		target = new(float64)
+	default:
+		return nil, nil
+	}
+
+	if err := json.Unmarshal(c.Value, target); err != nil {
+		return nil, fmt.Errorf("%w: bad sort value: %v", ports.ErrInvalidCursor, err)
+	}
+	switch value := target.(type) {
+	case *int:
+		return *value, nil
+	case *string:
+		return *value, nil
+	case *time.Time:
+		return *value, nil
+	default:
+		return *target.(*float64), nil
+	}
+}
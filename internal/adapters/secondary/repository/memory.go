@@ -0,0 +1,469 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"web-crawler-go/internal/core/domain"
+	"web-crawler-go/internal/core/ports"
+)
+
+// memoryKey identifies a product the way MongoDB upserts do: by name within a domain
+type memoryKey struct {
+	domain string
+	name   string
+}
+
+// MemoryRepository implements the ProductRepository interface in process memory,
+// for tests and ephemeral runs. It follows the MongoDB adapter's semantics:
+// products are replaced by domain and name and listed in first-insertion order
+// unless a sort key is given. Products are copied in and out, so callers cannot modify
+// stored values.
+type MemoryRepository struct {
+	mu       sync.RWMutex
+	products map[memoryKey]*memoryRecord
+	// order lists each domain's keys in insertion order; replacing a product keeps its position
+	order map[string][]memoryKey
+	// seq numbers products in insertion order, standing in for database IDs
+	seq   int64
+	bySeq map[int64]memoryKey
+	// lastCrawls keeps the latest crawl of each domain, all the catalogue shows
+	lastCrawls map[string]ports.CrawlRecord
+	logger     ports.Logger
+}
+
+// memoryRecord is a stored product with the bookkeeping the databases keep in
+// columns
+type memoryRecord struct {
+	seq           int64
+	product       *domain.Product
+	createdAt     time.Time
+	updatedAt     time.Time
+	lastCrawledAt time.Time
+}
+
+// snapshot returns a copy of the product with its ID and timestamps
+func (m *memoryRecord) snapshot() *domain.Product {
+	product := cloneProduct(m.product)
+	product.ID = strconv.FormatInt(m.seq, 10)
+	product.CreatedAt, product.UpdatedAt, product.LastCrawledAt = m.createdAt, m.updatedAt, m.lastCrawledAt
+	return product
+}
+
+// NewMemoryRepository creates an empty in-memory repository
+func NewMemoryRepository(logger ports.Logger) *MemoryRepository {
+	return &MemoryRepository{
+		products:   make(map[memoryKey]*memoryRecord),
+		order:      make(map[string][]memoryKey),
+		bySeq:      make(map[int64]memoryKey),
+		lastCrawls: make(map[string]ports.CrawlRecord),
+		logger:     logger,
+	}
+}
+
+// UpsertProduct stores a product, replacing the one with the same domain and name
+func (r *MemoryRepository) UpsertProduct(ctx context.Context, product *domain.Product) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.put(product)
+	return nil
+}
+
+// UpsertProducts stores a batch of products
+func (r *MemoryRepository) UpsertProducts(ctx context.Context, products []*domain.Product) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, product := range products {
+		r.put(product)
+	}
+	return len(products), nil
+}
+
+// put stores a copy of product; the caller holds the write lock
+func (r *MemoryRepository) put(product *domain.Product) {
+	key := memoryKey{domain: product.Domain, name: product.Name}
+	now := time.Now()
+	record, exists := r.products[key]
+	if !exists {
+		r.seq++
+		record = &memoryRecord{seq: r.seq, createdAt: now}
+		r.products[key] = record
+		r.order[key.domain] = append(r.order[key.domain], key)
+		r.bySeq[record.seq] = key
+	}
+	runID := product.CrawlRunID
+	if runID != "" {
+		record.lastCrawledAt = now
+	} else if record.product != nil {
+		// Saves outside a crawl keep the last crawl
+		runID = record.product.CrawlRunID
+	}
+	record.product = cloneProduct(product)
+	record.product.CrawlRunID = runID
+	record.updatedAt = now
+}
+
+// Close implements ports.ProductRepository; there is nothing to release
+func (r *MemoryRepository) Close(ctx context.Context) error {
+	return nil
+}
+
+// GetProducts returns a page of the products matching the query
+func (r *MemoryRepository) GetProducts(ctx context.Context, query ports.ProductQuery) (*ports.ProductPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	after, err := decodeProductCursor(query)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	records := r.match(query)
+	sortRecords(records, query)
+
+	start := min(query.Offset(), len(records))
+	if after != nil {
+		if start, err = startAfter(records, query, after); err != nil {
+			return nil, err
+		}
+	}
+	end := min(start+max(query.PageSize, 0), len(records))
+
+	page := &ports.ProductPage{Products: make([]*domain.Product, 0, end-start)}
+	for _, record := range records[start:end] {
+		page.Products = append(page.Products, record.snapshot())
+	}
+	if end < len(records) && end > start {
+		last := records[end-1]
+		page.NextCursor, err = encodeProductCursor(query, last.sortValue(query.Sort), strconv.FormatInt(last.seq, 10))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// GetProduct returns the product with the ID, its sequence number
+func (r *MemoryRepository) GetProduct(ctx context.Context, id string) (*domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	seq, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, ports.ErrProductNotFound
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.bySeq[seq]
+	if !ok {
+		return nil, ports.ErrProductNotFound
+	}
+	return r.products[key].snapshot(), nil
+}
+
+// GetTotalProducts counts the products matching the query
+func (r *MemoryRepository) GetTotalProducts(ctx context.Context, query ports.ProductQuery) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.match(query)), nil
+}
+
+// RecordCrawl keeps the crawl if it is the latest of its domain
+func (r *MemoryRepository) RecordCrawl(ctx context.Context, crawl ports.CrawlRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if last, ok := r.lastCrawls[crawl.Domain]; !ok || !crawl.FinishedAt.Before(last.FinishedAt) {
+		r.lastCrawls[crawl.Domain] = crawl
+	}
+	return nil
+}
+
+// ListDomains returns a page of the domains with products or crawls
+func (r *MemoryRepository) ListDomains(ctx context.Context, query ports.DomainQuery) (*ports.DomainPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	summaries := make(map[string]*ports.DomainSummary)
+	summary := func(name string) *ports.DomainSummary {
+		if s, ok := summaries[name]; ok {
+			return s
+		}
+		s := &ports.DomainSummary{Domain: name, StatusCounts: make(map[string]int)}
+		summaries[name] = s
+		return s
+	}
+	for name, keys := range r.order {
+		s := summary(name)
+		for _, key := range keys {
+			product := r.products[key].product
+			s.ProductCount++
+			s.StatusCounts[product.Status]++
+			s.Provider = max(s.Provider, product.Provider)
+		}
+	}
+	for name, crawl := range r.lastCrawls {
+		s := summary(name)
+		s.LastCrawl = &crawl
+		if s.Provider == "" {
+			s.Provider = crawl.Provider
+		}
+	}
+
+	domains := make([]*ports.DomainSummary, 0, len(summaries))
+	for _, s := range summaries {
+		domains = append(domains, s)
+	}
+	slices.SortFunc(domains, func(a, b *ports.DomainSummary) int {
+		if query.Sort == ports.SortLastCrawledAt && (a.LastCrawl == nil) != (b.LastCrawl == nil) {
+			// Never crawled domains last in either direction
+			if a.LastCrawl == nil {
+				return 1
+			}
+			return -1
+		}
+		c := 0
+		switch query.Sort {
+		case ports.SortProductCount:
+			c = cmp.Compare(a.ProductCount, b.ProductCount)
+		case ports.SortLastCrawledAt:
+			if a.LastCrawl != nil {
+				c = a.LastCrawl.FinishedAt.Compare(b.LastCrawl.FinishedAt)
+			}
+		}
+		if c == 0 {
+			c = strings.Compare(a.Domain, b.Domain)
+		}
+		if query.Descending {
+			c = -c
+		}
+		return c
+	})
+
+	start := min(query.Offset(), len(domains))
+	end := min(start+query.PageSize, len(domains))
+	return &ports.DomainPage{Domains: domains[start:end], TotalItems: len(domains)}, nil
+}
+
+// GetDomainStats summarises the products of a domain
+func (r *MemoryRepository) GetDomainStats(ctx context.Context, domainName string) (*ports.DomainStats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := &ports.DomainStats{Domain: domainName, TopTags: make([]ports.TagCount, 0)}
+	keys := r.order[domainName]
+	if len(keys) == 0 {
+		return stats, nil
+	}
+
+	prices := make([]int, 0, len(keys))
+	tagCounts := make(map[string]int)
+	images, discounts := 0, 0.0
+	for _, key := range keys {
+		product := r.products[key].product
+		stats.ProductCount++
+		if product.InStock() {
+			stats.InStockCount++
+		}
+		if product.OnSale() {
+			stats.OnSaleCount++
+			discounts += product.DiscountPercent()
+		}
+		for _, tag := range product.Tags {
+			tagCounts[tag]++
+		}
+		images += len(product.ImagesURL)
+		prices = append(prices, product.Price)
+	}
+
+	stats.InStockRatio = float64(stats.InStockCount) / float64(stats.ProductCount)
+	if stats.OnSaleCount > 0 {
+		stats.AverageDiscount = discounts / float64(stats.OnSaleCount)
+	}
+	stats.ImagesPerProduct = float64(images) / float64(stats.ProductCount)
+
+	slices.Sort(prices)
+	n := len(prices)
+	stats.Price = ports.PriceStats{
+		Min:     prices[0],
+		Median:  float64(prices[(n-1)/2]+prices[n/2]) / 2,
+		Max:     prices[n-1],
+		Buckets: ports.NewPriceBuckets(prices[0], prices[n-1]),
+	}
+	width := stats.Price.Buckets[0].Max - stats.Price.Buckets[0].Min
+	for _, price := range prices {
+		stats.Price.Buckets[(price-prices[0])/width].Count++
+	}
+
+	for tag, count := range tagCounts {
+		stats.TopTags = append(stats.TopTags, ports.TagCount{Tag: tag, Count: count})
+	}
+	slices.SortFunc(stats.TopTags, func(a, b ports.TagCount) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Tag, b.Tag)
+	})
+	stats.TopTags = stats.TopTags[:min(len(stats.TopTags), ports.DomainStatsTopTags)]
+
+	return stats, nil
+}
+
+// match returns the domain's records passing the query's filters, in insertion
+// order; the caller holds the lock
+func (r *MemoryRepository) match(query ports.ProductQuery) []*memoryRecord {
+	search := strings.ToLower(query.Search)
+
+	var records []*memoryRecord
+	for _, key := range r.order[query.Domain] {
+		record := r.products[key]
+		product := record.product
+		switch {
+		case query.Status != "" && product.Status != query.Status,
+			query.Tag != "" && !slices.Contains(product.Tags, query.Tag),
+			query.MinPrice != nil && product.Price < *query.MinPrice,
+			query.MaxPrice != nil && product.Price > *query.MaxPrice,
+			query.OnSale && !product.OnSale(),
+			!query.UpdatedSince.IsZero() && record.updatedAt.Before(query.UpdatedSince),
+			!query.CreatedSince.IsZero() && record.createdAt.Before(query.CreatedSince),
+			!query.CrawledSince.IsZero() && (record.lastCrawledAt.IsZero() || record.lastCrawledAt.Before(query.CrawledSince)),
+			!query.CrawledBefore.IsZero() && (record.lastCrawledAt.IsZero() || !record.lastCrawledAt.Before(query.CrawledBefore)),
+			query.CrawlRunID != "" && product.CrawlRunID != query.CrawlRunID,
+			query.Provider != "" && product.Provider != query.Provider,
+			search != "" && !strings.Contains(strings.ToLower(product.Name+" "+product.Description), search):
+			continue
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// sortValue is the record's value for a sort key, nil for insertion order
+func (m *memoryRecord) sortValue(key ports.ProductSortKey) any {
+	switch key {
+	case ports.SortPrice:
+		return m.product.Price
+	case ports.SortName:
+		return m.product.Name
+	case ports.SortUpdatedAt:
+		return m.updatedAt
+	case ports.SortDiscount:
+		return m.product.DiscountPercent()
+	default:
+		return nil
+	}
+}
+
+// compareSortValues compares two values of the same sort key
+func compareSortValues(a, b any) int {
+	switch a := a.(type) {
+	case int:
+		return cmp.Compare(a, b.(int))
+	case string:
+		return strings.Compare(a, b.(string))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case float64:
+		return cmp.Compare(a, b.(float64))
+	default:
+		return 0
+	}
+}
+
+// compareRecord orders a record against a sort value and sequence number in
+// ascending order, breaking ties by sequence like the databases do by ID
+func compareRecord(record *memoryRecord, key ports.ProductSortKey, value any, seq int64) int {
+	if c := compareSortValues(record.sortValue(key), value); c != 0 {
+		return c
+	}
+	return cmp.Compare(record.seq, seq)
+}
+
+// sortRecords orders records by the query's sort key and sequence number
+func sortRecords(records []*memoryRecord, query ports.ProductQuery) {
+	slices.SortFunc(records, func(a, b *memoryRecord) int {
+		c := compareRecord(a, query.Sort, b.sortValue(query.Sort), b.seq)
+		if query.Descending {
+			return -c
+		}
+		return c
+	})
+}
+
+// startAfter returns the position of the first sorted record that comes after the
+// cursor
+func startAfter(records []*memoryRecord, query ports.ProductQuery, after *productCursor) (int, error) {
+	seq, err := strconv.ParseInt(after.ID, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: bad ID: %v", ports.ErrInvalidCursor, err)
+	}
+	value, err := after.sortValue()
+	if err != nil {
+		return 0, err
+	}
+
+	start, _ := slices.BinarySearchFunc(records, struct{}{}, func(record *memoryRecord, _ struct{}) int {
+		c := compareRecord(record, query.Sort, value, seq)
+		if query.Descending {
+			c = -c
+		}
+		// Records equal to the cursor belong to the previous page
+		if c == 0 {
+			return -1
+		}
+		return c
+	})
+	return start, nil
+}
+
+// cloneProduct copies a product including its slices
+func cloneProduct(product *domain.Product) *domain.Product {
+	clone := *product
+	clone.ImagesURL = slices.Clone(product.ImagesURL)
+	clone.Tags = slices.Clone(product.Tags)
+	clone.Variants = slices.Clone(product.Variants)
+	for i := range clone.Variants {
+		clone.Variants[i].Options = slices.Clone(clone.Variants[i].Options)
+	}
+	return &clone
+}
+
+// Ensure MemoryRepository implements ProductRepository
+var _ ports.ProductRepository = (*MemoryRepository)(nil)
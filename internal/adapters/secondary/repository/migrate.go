@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"web-crawler-go/internal/core/ports"
+)
+
+// migrations holds the schema of the SQL repositories, one directory per dialect
+//
+//go:embed migrations
+var migrations embed.FS
+
+// runMigrations applies, in file name order, every migration of the dialect that
+// is not yet recorded in schema_migrations. Each migration runs in its own
+// transaction. bind is the dialect's first positional placeholder ("$1" or "?").
+func runMigrations(ctx context.Context, db *sql.DB, dialect, bind string, logger ports.Logger) error {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    TEXT PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	dir := "migrations/" + dialect
+	entries, err := fs.ReadDir(migrations, dir)
+	if err != nil {
+		return fmt.Errorf("failed to list %s migrations: %w", dialect, err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".sql") {
+			files = append(files, entry.Name())
+		}
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		version := strings.TrimSuffix(file, ".sql")
+
+		var applied int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_migrations WHERE version = "+bind, version).Scan(&applied); err != nil {
+			return fmt.Errorf("failed to check migration %s: %w", version, err)
+		}
+		if applied > 0 {
+			continue
+		}
+
+		script, err := migrations.ReadFile(dir + "/" + file)
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %w", version, err)
+		}
+		if err := applyMigration(ctx, db, version, string(script), bind); err != nil {
+			return err
+		}
+		logger.Info("applied database migration", "dialect", dialect, "version", version)
+	}
+	return nil
+}
+
+// applyMigration runs one migration script and records it in a single transaction
+func applyMigration(ctx context.Context, db *sql.DB, version, script, bind string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %s: %w", version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("failed to apply migration %s: %w", version, err)
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version) VALUES ("+bind+")", version); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %s: %w", version, err)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS products (
+    id               BIGSERIAL PRIMARY KEY,
+    domain           TEXT        NOT NULL,
+    name             TEXT        NOT NULL,
+    price            INTEGER     NOT NULL DEFAULT 0,
+    price_discounted INTEGER     NOT NULL DEFAULT 0,
+    description      TEXT        NOT NULL DEFAULT '',
+    images_url       JSONB       NOT NULL DEFAULT '[]'::jsonb,
+    tags             JSONB       NOT NULL DEFAULT '[]'::jsonb,
+    status           TEXT        NOT NULL DEFAULT '',
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- A product is identified by its name within a domain, as in MongoDB
+    CONSTRAINT products_domain_name_key UNIQUE (domain, name)
+);
+
+-- Listing a domain in insertion order
+CREATE INDEX IF NOT EXISTS products_domain_id_idx ON products (domain, id);
+
+-- Tag containment queries, e.g. tags @> '["sale"]'
+CREATE INDEX IF NOT EXISTS products_tags_idx ON products USING GIN (tags);
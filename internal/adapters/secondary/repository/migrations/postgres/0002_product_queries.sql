@@ -0,0 +1,18 @@
+-- Discount percentage, for on-sale filtering and sorting by discount
+ALTER TABLE products ADD COLUMN IF NOT EXISTS discount_percent DOUBLE PRECISION GENERATED ALWAYS AS (
+    CASE WHEN price_discounted > 0 AND price_discounted < price
+         THEN ((price - price_discounted) * 100.0 / price)::double precision
+         ELSE 0
+    END
+) STORED;
+
+-- Filters and sort keys within a domain; id breaks ties
+CREATE INDEX IF NOT EXISTS products_domain_status_idx     ON products (domain, status, id);
+CREATE INDEX IF NOT EXISTS products_domain_price_idx      ON products (domain, price, id);
+CREATE INDEX IF NOT EXISTS products_domain_name_idx       ON products (domain, name, id);
+CREATE INDEX IF NOT EXISTS products_domain_updated_at_idx ON products (domain, updated_at, id);
+CREATE INDEX IF NOT EXISTS products_domain_discount_idx   ON products (domain, discount_percent, id);
+
+-- Word search over name and description
+CREATE INDEX IF NOT EXISTS products_search_idx ON products
+    USING GIN (to_tsvector('simple', name || ' ' || description));
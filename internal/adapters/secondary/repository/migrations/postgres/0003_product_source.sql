@@ -0,0 +1,4 @@
+-- Where each product was crawled from
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS source_url TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS provider   TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,15 @@
+-- The outcome of every crawl, for the domain catalogue
+CREATE TABLE IF NOT EXISTS crawls (
+    id             BIGSERIAL PRIMARY KEY,
+    run_id         TEXT        NOT NULL,
+    domain         TEXT        NOT NULL,
+    provider       TEXT        NOT NULL DEFAULT '',
+    status         TEXT        NOT NULL,
+    products_count INTEGER     NOT NULL DEFAULT 0,
+    error          TEXT        NOT NULL DEFAULT '',
+    started_at     TIMESTAMPTZ NOT NULL,
+    finished_at    TIMESTAMPTZ NOT NULL
+);
+
+-- Latest crawl of a domain
+CREATE INDEX IF NOT EXISTS crawls_domain_finished_at_idx ON crawls (domain, finished_at);
@@ -0,0 +1,8 @@
+-- The crawl that last saved each product, and when
+ALTER TABLE products ADD COLUMN IF NOT EXISTS crawl_run_id    TEXT NOT NULL DEFAULT '';
+ALTER TABLE products ADD COLUMN IF NOT EXISTS last_crawled_at TIMESTAMPTZ;
+
+-- Provenance filters within a domain; id breaks ties
+CREATE INDEX IF NOT EXISTS products_domain_created_at_idx      ON products (domain, created_at, id);
+CREATE INDEX IF NOT EXISTS products_domain_last_crawled_at_idx ON products (domain, last_crawled_at, id);
+CREATE INDEX IF NOT EXISTS products_domain_crawl_run_id_idx    ON products (domain, crawl_run_id, id);
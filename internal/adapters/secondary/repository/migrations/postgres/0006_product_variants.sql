@@ -0,0 +1,2 @@
+-- Purchasable options of each product: options, sku, price, price_discounted, quantity
+ALTER TABLE products ADD COLUMN IF NOT EXISTS variants JSONB NOT NULL DEFAULT '[]'::jsonb;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS products (
+    id               INTEGER PRIMARY KEY AUTOINCREMENT,
+    domain           TEXT    NOT NULL,
+    name             TEXT    NOT NULL,
+    price            INTEGER NOT NULL DEFAULT 0,
+    price_discounted INTEGER NOT NULL DEFAULT 0,
+    description      TEXT    NOT NULL DEFAULT '',
+    -- JSON arrays
+    images_url       TEXT    NOT NULL DEFAULT '[]',
+    tags             TEXT    NOT NULL DEFAULT '[]',
+    status           TEXT    NOT NULL DEFAULT '',
+    created_at       TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    updated_at       TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    -- A product is identified by its name within a domain, as in MongoDB
+    UNIQUE (domain, name)
+);
+
+-- Listing a domain in insertion order
+CREATE INDEX IF NOT EXISTS products_domain_id_idx ON products (domain, id);
@@ -0,0 +1,3 @@
+-- Where each product was crawled from
+ALTER TABLE products ADD COLUMN source_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE products ADD COLUMN provider   TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,16 @@
+-- The outcome of every crawl, for the domain catalogue
+CREATE TABLE IF NOT EXISTS crawls (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id         TEXT    NOT NULL,
+    domain         TEXT    NOT NULL,
+    provider       TEXT    NOT NULL DEFAULT '',
+    status         TEXT    NOT NULL,
+    products_count INTEGER NOT NULL DEFAULT 0,
+    error          TEXT    NOT NULL DEFAULT '',
+    -- Written in sqliteTimeLayout so they compare as text
+    started_at     TEXT    NOT NULL,
+    finished_at    TEXT    NOT NULL
+);
+
+-- Latest crawl of a domain
+CREATE INDEX IF NOT EXISTS crawls_domain_finished_at_idx ON crawls (domain, finished_at);
@@ -0,0 +1,2 @@
+-- Purchasable options of each product as a JSON array: options, sku, price, price_discounted, quantity
+ALTER TABLE products ADD COLUMN variants TEXT NOT NULL DEFAULT '[]';
@@ -2,11 +2,13 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"log"
+	"time"
 	"web-crawler-go/internal/core/domain"
 	"web-crawler-go/internal/core/ports"
 )
@@ -15,7 +17,9 @@ import (
 type MongoDBRepository struct {
 	client     *mongo.Client
 	collection *mongo.Collection
-	logger     ports.Logger
+	// crawls holds a CrawlRecord per crawl, next to the products collection
+	crawls *mongo.Collection
+	logger ports.Logger
 }
 
 // NewMongoDBRepository creates a new MongoDB repository
@@ -44,40 +48,235 @@ func NewMongoDBRepository(ctx context.Context, connectionURI, dbName, collection
 	// Get a handle to the specified database and collection
 	collection := client.Database(dbName).Collection(collectionName)
 
+	crawls := client.Database(dbName).Collection(collectionName + "_crawls")
+
+	if err := ensureIndexes(ctx, collection, crawls); err != nil {
+		return nil, err
+	}
+	if err := backfillProducts(ctx, collection); err != nil {
+		return nil, err
+	}
+
 	logger.Info("connected to MongoDB", "database", dbName, "collection", collectionName)
 
 	return &MongoDBRepository{
 		client:     client,
 		collection: collection,
+		crawls:     crawls,
 		logger:     logger,
 	}, nil
 }
 
-// UpsertProduct saves a product to MongoDB
-func (m *MongoDBRepository) UpsertProduct(ctx context.Context, product *domain.Product) error {
-	m.logger.Info("upserting product to MongoDB", "name", product.Name)
+// productDocument is how a product is stored. Fields the API filters and sorts on
+// but that are derived from the product are kept at the top level so they can be
+// indexed.
+type productDocument struct {
+	ID            bson.ObjectID `bson:"_id,omitempty"`
+	Domain        string        `bson:"domain"`
+	Data          productData   `bson:"data"`
+	CreatedAt     time.Time     `bson:"created_at"`
+	UpdatedAt     time.Time     `bson:"updated_at"`
+	LastCrawledAt time.Time     `bson:"last_crawled_at,omitempty"`
+	CrawlRunID    string        `bson:"crawl_run_id,omitempty"`
+	Discount      float64       `bson:"discount"`
+}
+
+// productData holds the product's own fields. Their names are the driver's
+// defaults for domain.Product, which was stored here directly before IDs and
+// timestamps were added to it; those live in the document instead.
+type productData struct {
+	Domain          string         `bson:"domain"`
+	Name            string         `bson:"name"`
+	Price           int            `bson:"price"`
+	PriceDiscounted int            `bson:"pricediscounted"`
+	Description     string         `bson:"description"`
+	ImagesURL       []string       `bson:"imagesurl"`
+	Tags            []string       `bson:"tags"`
+	Variants        []mongoVariant `bson:"variants,omitempty"`
+	Status          string         `bson:"status"`
+	SourceURL       string         `bson:"sourceurl,omitempty"`
+	Provider        string         `bson:"provider,omitempty"`
+}
+
+// mongoVariant is how a product variant is stored
+type mongoVariant struct {
+	Options         []string `bson:"options"`
+	SKU             string   `bson:"sku,omitempty"`
+	Price           int      `bson:"price"`
+	PriceDiscounted int      `bson:"price_discounted"`
+	Quantity        int      `bson:"quantity"`
+}
 
-	type Document struct {
-		Domain string         `bson:"domain"`
-		Data   domain.Product `bson:"data"`
+// productUpdate upserts a product: its fields are written with $set and its
+// first-seen time with $setOnInsert, so saving it again keeps created_at. The
+// crawl run and crawl time are only set by crawls.
+func productUpdate(product *domain.Product, now time.Time) bson.M {
+	set := bson.M{
+		"domain": product.Domain,
+		"data": productData{
+			Domain:          product.Domain,
+			Name:            product.Name,
+			Price:           product.Price,
+			PriceDiscounted: product.PriceDiscounted,
+			Description:     product.Description,
+			ImagesURL:       product.ImagesURL,
+			Tags:            product.Tags,
+			Variants:        toMongoVariants(product.Variants),
+			Status:          product.Status,
+			SourceURL:       product.SourceURL,
+			Provider:        product.Provider,
+		},
+		"updated_at": now,
+		"discount":   product.DiscountPercent(),
 	}
+	if product.CrawlRunID != "" {
+		set["crawl_run_id"] = product.CrawlRunID
+		set["last_crawled_at"] = now
+	}
+	return bson.M{"$set": set, "$setOnInsert": bson.M{"created_at": now}}
+}
+
+// toMongoVariants converts the variants for storage
+func toMongoVariants(variants []domain.ProductVariant) []mongoVariant {
+	var stored []mongoVariant
+	for _, variant := range variants {
+		stored = append(stored, mongoVariant{
+			Options:         variant.Options,
+			SKU:             variant.SKU,
+			Price:           variant.Price,
+			PriceDiscounted: variant.PriceDiscounted,
+			Quantity:        variant.Quantity,
+		})
+	}
+	return stored
+}
 
-	document := Document{
-		Domain: "attfrench.cross-right.tw",
-		Data:   *product,
+// product converts the document back
+func (d productDocument) product() *domain.Product {
+	var variants []domain.ProductVariant
+	for _, variant := range d.Data.Variants {
+		variants = append(variants, domain.ProductVariant{
+			Options:         variant.Options,
+			SKU:             variant.SKU,
+			Price:           variant.Price,
+			PriceDiscounted: variant.PriceDiscounted,
+			Quantity:        variant.Quantity,
+		})
+	}
+	return &domain.Product{
+		ID:              d.ID.Hex(),
+		Domain:          d.Domain,
+		Name:            d.Data.Name,
+		Price:           d.Data.Price,
+		PriceDiscounted: d.Data.PriceDiscounted,
+		Description:     d.Data.Description,
+		ImagesURL:       d.Data.ImagesURL,
+		Tags:            d.Data.Tags,
+		Variants:        variants,
+		Status:          d.Data.Status,
+		SourceURL:       d.Data.SourceURL,
+		Provider:        d.Data.Provider,
+		CrawlRunID:      d.CrawlRunID,
+		CreatedAt:       d.CreatedAt,
+		UpdatedAt:       d.UpdatedAt,
+		LastCrawledAt:   d.LastCrawledAt,
 	}
+}
 
-	// Define the filter to find existing document
-	filter := map[string]interface{}{
-		"domain":    document.Domain,
-		"data.name": product.Name,
+// sortValue is the document's value for a sort key, as a cursor records it
+func (d productDocument) sortValue(key ports.ProductSortKey) any {
+	switch key {
+	case ports.SortPrice:
+		return d.Data.Price
+	case ports.SortName:
+		return d.Data.Name
+	case ports.SortUpdatedAt:
+		return d.UpdatedAt
+	case ports.SortDiscount:
+		return d.Discount
+	default:
+		return nil
 	}
+}
 
-	// Set upsert option to true
-	opts := options.Replace().SetUpsert(true)
+// ensureIndexes creates the indexes behind upserts, product filters, sort keys,
+// text search and crawl lookups. Creating an index that already exists is a no-op.
+func ensureIndexes(ctx context.Context, collection, crawls *mongo.Collection) error {
+	byDomain := func(keys ...bson.E) bson.D {
+		return append(append(bson.D{{Key: "domain", Value: 1}}, keys...), bson.E{Key: "_id", Value: 1})
+	}
+	models := []mongo.IndexModel{
+		// A product is identified by its domain and name; the unique index makes
+		// concurrent upserts of the same product update one document
+		{
+			Keys:    bson.D{{Key: "domain", Value: 1}, {Key: "data.name", Value: 1}},
+			Options: options.Index().SetName("product_identity").SetUnique(true),
+		},
+		// Sorting by name
+		{Keys: byDomain(bson.E{Key: "data.name", Value: 1})},
+		{Keys: byDomain(bson.E{Key: "data.status", Value: 1})},
+		{Keys: byDomain(bson.E{Key: "data.tags", Value: 1})},
+		{Keys: byDomain(bson.E{Key: "data.price", Value: 1})},
+		{Keys: byDomain(bson.E{Key: "updated_at", Value: 1})},
+		{Keys: byDomain(bson.E{Key: "discount", Value: 1})},
+		{Keys: byDomain(bson.E{Key: "created_at", Value: 1})},
+		{Keys: byDomain(bson.E{Key: "last_crawled_at", Value: 1})},
+		{Keys: byDomain(bson.E{Key: "crawl_run_id", Value: 1})},
+		{
+			Keys:    bson.D{{Key: "data.name", Value: "text"}, {Key: "data.description", Value: "text"}},
+			Options: options.Index().SetName("product_text"),
+		},
+	}
+	if _, err := collection.Indexes().CreateMany(ctx, models); err != nil {
+		return fmt.Errorf("failed to create MongoDB indexes: %w", err)
+	}
 
-	// Upsert the product into the collection
-	result, err := m.collection.ReplaceOne(ctx, filter, document, opts)
+	// Latest crawl of a domain
+	crawlIndex := mongo.IndexModel{Keys: bson.D{{Key: "domain", Value: 1}, {Key: "finished_at", Value: -1}}}
+	if _, err := crawls.Indexes().CreateOne(ctx, crawlIndex); err != nil {
+		return fmt.Errorf("failed to create MongoDB crawl index: %w", err)
+	}
+	return nil
+}
+
+// backfillProducts fills in the top-level fields of products saved before they
+// were stored. created_at and updated_at take the time embedded in the ObjectID,
+// which the server assigned when the product was first upserted, and discount is
+// computed from the prices as domain.Product.DiscountPercent does. Without them
+// legacy products would be missed by the on_sale and updated_since filters and
+// sort as null.
+func backfillProducts(ctx context.Context, collection *mongo.Collection) error {
+	onSale := bson.M{"$and": bson.A{
+		bson.M{"$gt": bson.A{"$data.pricediscounted", 0}},
+		bson.M{"$lt": bson.A{"$data.pricediscounted", "$data.price"}},
+	}}
+	discount := bson.M{"$divide": bson.A{
+		bson.M{"$multiply": bson.A{bson.M{"$subtract": bson.A{"$data.price", "$data.pricediscounted"}}, 100}},
+		"$data.price",
+	}}
+	fields := []struct {
+		name  string
+		value any
+	}{
+		{"created_at", bson.M{"$toDate": "$_id"}},
+		{"updated_at", bson.M{"$toDate": "$_id"}},
+		{"discount", bson.M{"$cond": bson.A{onSale, discount, 0}}},
+	}
+	for _, field := range fields {
+		filter := bson.M{field.name: bson.M{"$exists": false}}
+		update := bson.A{bson.M{"$set": bson.M{field.name: field.value}}}
+		if _, err := collection.UpdateMany(ctx, filter, update); err != nil {
+			return fmt.Errorf("failed to backfill product %s: %w", field.name, err)
+		}
+	}
+	return nil
+}
+
+// UpsertProduct saves a product to MongoDB
+func (m *MongoDBRepository) UpsertProduct(ctx context.Context, product *domain.Product) error {
+	m.logger.Info("upserting product to MongoDB", "name", product.Name)
+
+	result, err := m.upsertOne(ctx, product, time.Now())
 	if err != nil {
 		m.logger.Error("failed to upsert product to MongoDB", "error", err)
 		return fmt.Errorf("failed to upsert product to MongoDB: %w", err)
@@ -92,6 +291,93 @@ func (m *MongoDBRepository) UpsertProduct(ctx context.Context, product *domain.P
 	return nil
 }
 
+// productFilter selects the document of a product by its identity
+func productFilter(product *domain.Product) bson.M {
+	return bson.M{"domain": product.Domain, "data.name": product.Name}
+}
+
+// upsertOne saves a single product. When a concurrent upsert of the same product
+// inserts it first, the unique index rejects the second insert with a duplicate
+// key error; trying again then finds and updates that document.
+func (m *MongoDBRepository) upsertOne(ctx context.Context, product *domain.Product, now time.Time) (*mongo.UpdateResult, error) {
+	opts := options.UpdateOne().SetUpsert(true)
+	result, err := m.collection.UpdateOne(ctx, productFilter(product), productUpdate(product, now), opts)
+	if mongo.IsDuplicateKeyError(err) {
+		m.logger.Warn("retrying product upsert after a duplicate key error", "name", product.Name)
+		result, err = m.collection.UpdateOne(ctx, productFilter(product), productUpdate(product, now), opts)
+	}
+	return result, err
+}
+
+// mongoBatchSize is how many writes are sent per BulkWrite call
+const mongoBatchSize = 500
+
+// UpsertProducts saves a batch with unordered bulk writes, so one failing product
+// does not stop the others. Failures are reported per product in a
+// *ports.ProductUpsertError.
+func (m *MongoDBRepository) UpsertProducts(ctx context.Context, products []*domain.Product) (int, error) {
+	m.logger.Info("upserting products to MongoDB", "count", len(products))
+
+	var failures []ports.ProductUpsertFailure
+	saved := 0
+	opts := options.BulkWrite().SetOrdered(false)
+	now := time.Now()
+
+	for start := 0; start < len(products); start += mongoBatchSize {
+		chunk := products[start:min(start+mongoBatchSize, len(products))]
+
+		models := make([]mongo.WriteModel, len(chunk))
+		for i, product := range chunk {
+			models[i] = mongo.NewUpdateOneModel().
+				SetFilter(productFilter(product)).
+				SetUpdate(productUpdate(product, now)).
+				SetUpsert(true)
+		}
+
+		result, err := m.collection.BulkWrite(ctx, models, opts)
+		if err == nil {
+			saved += len(chunk)
+			m.logger.Info("bulk upserted products to MongoDB", "upserted", result.UpsertedCount, "modified", result.ModifiedCount)
+			continue
+		}
+
+		var bulkErr mongo.BulkWriteException
+		if !errors.As(err, &bulkErr) || len(bulkErr.WriteErrors) == 0 {
+			m.logger.Error("failed to bulk upsert products to MongoDB", "error", err)
+			return saved, fmt.Errorf("failed to bulk upsert products to MongoDB: %w", err)
+		}
+
+		// Unordered writes carry on past errors, so everything not listed was saved.
+		// Products a concurrent upsert inserted first are saved again one by one.
+		saved += len(chunk) - len(bulkErr.WriteErrors)
+		failed := 0
+		for _, writeErr := range bulkErr.WriteErrors {
+			product := chunk[writeErr.Index]
+			var productErr error = writeErr
+			if mongo.IsDuplicateKeyError(writeErr) {
+				if _, productErr = m.upsertOne(ctx, product, now); productErr == nil {
+					saved++
+					continue
+				}
+			}
+			failures = append(failures, ports.ProductUpsertFailure{
+				Index: start + writeErr.Index,
+				Name:  product.Name,
+				Err:   productErr,
+			})
+			failed++
+		}
+		if failed > 0 {
+			m.logger.Error("some products failed to upsert to MongoDB", "failed", failed, "error", err)
+		}
+	}
+
+	if len(failures) > 0 {
+		return saved, &ports.ProductUpsertError{Failures: failures}
+	}
+	return saved, nil
+}
+
 // Close closes the MongoDB connection
 func (m *MongoDBRepository) Close(ctx context.Context) error {
 	m.logger.Info("closing MongoDB connection")
@@ -102,18 +388,28 @@ func (m *MongoDBRepository) Close(ctx context.Context) error {
 	return nil
 }
 
-func (m *MongoDBRepository) GetProducts(ctx context.Context, domainName string, page, pageSize int) ([]*domain.Product, error) {
-	m.logger.Info("getting products from MongoDB", "domainName", domainName, "page", page, "pageSize", pageSize)
+// GetProducts returns a page of the products matching the query. Cursor pages
+// are selected with a keyset condition on the sort key and _id instead of $skip.
+func (m *MongoDBRepository) GetProducts(ctx context.Context, query ports.ProductQuery) (*ports.ProductPage, error) {
+	m.logger.Info("getting products from MongoDB", "domainName", query.Domain, "page", query.Page, "pageSize", query.PageSize, "sort", query.Sort, "cursor", query.Cursor != "")
 
-	// Calculate skip value for pagination
-	skip := (page - 1) * pageSize
+	filter := mongoProductFilter(query)
+	after, err := decodeProductCursor(query)
+	if err != nil {
+		return nil, err
+	}
+	if after != nil {
+		if err := addMongoKeyset(filter, after); err != nil {
+			return nil, err
+		}
+	}
 
-	// Use aggregation pipeline to return only the data content
+	// One extra document tells whether there is a next page
 	pipeline := bson.A{
-		bson.M{"$match": bson.M{"domain": domainName}},
-		bson.M{"$replaceRoot": bson.M{"newRoot": "$data"}},
-		bson.M{"$skip": skip},
-		bson.M{"$limit": pageSize},
+		bson.M{"$match": filter},
+		bson.M{"$sort": mongoProductSort(query)},
+		bson.M{"$skip": query.Offset()},
+		bson.M{"$limit": query.PageSize + 1},
 	}
 
 	cursor, err := m.collection.Aggregate(ctx, pipeline)
@@ -123,27 +419,62 @@ func (m *MongoDBRepository) GetProducts(ctx context.Context, domainName string,
 	}
 	defer cursor.Close(ctx)
 
-	products := make([]*domain.Product, 0)
+	var documents []productDocument
 	for cursor.Next(ctx) {
-		var product domain.Product
-		if err := cursor.Decode(&product); err != nil {
+		var document productDocument
+		if err := cursor.Decode(&document); err != nil {
 			m.logger.Error("failed to decode document", "error", err)
 			return nil, fmt.Errorf("failed to decode document: %w", err)
 		}
-		products = append(products, &product)
+		documents = append(documents, document)
 	}
 	if err := cursor.Err(); err != nil {
 		m.logger.Error("failed to iterate cursor", "error", err)
 		return nil, fmt.Errorf("failed to iterate cursor: %w", err)
 	}
-	m.logger.Info("successfully fetched products", "count", len(products))
-	return products, nil
+
+	page := &ports.ProductPage{Products: make([]*domain.Product, 0, min(len(documents), query.PageSize))}
+	if len(documents) > query.PageSize {
+		documents = documents[:query.PageSize]
+		last := documents[len(documents)-1]
+		page.NextCursor, err = encodeProductCursor(query, last.sortValue(query.Sort), last.ID.Hex())
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, document := range documents {
+		page.Products = append(page.Products, document.product())
+	}
+
+	m.logger.Info("successfully fetched products", "count", len(page.Products))
+	return page, nil
+}
+
+// GetProduct returns the product with the ID, a hex ObjectID
+func (m *MongoDBRepository) GetProduct(ctx context.Context, id string) (*domain.Product, error) {
+	m.logger.Info("getting product from MongoDB", "id", id)
+
+	objectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ports.ErrProductNotFound
+	}
+
+	var document productDocument
+	if err := m.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&document); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ports.ErrProductNotFound
+		}
+		m.logger.Error("failed to find product", "id", id, "error", err)
+		return nil, fmt.Errorf("failed to find product: %w", err)
+	}
+	return document.product(), nil
 }
 
-func (m *MongoDBRepository) GetTotalProducts(ctx context.Context, domainName string) (int, error) {
-	m.logger.Info("getting total products from MongoDB", "domainName", domainName)
+// GetTotalProducts counts the products matching the query
+func (m *MongoDBRepository) GetTotalProducts(ctx context.Context, query ports.ProductQuery) (int, error) {
+	m.logger.Info("getting total products from MongoDB", "domainName", query.Domain)
 
-	totalCount, err := m.collection.CountDocuments(ctx, bson.M{"domain": domainName})
+	totalCount, err := m.collection.CountDocuments(ctx, mongoProductFilter(query))
 	if err != nil {
 		m.logger.Error("failed to count documents", "error", err)
 		return 0, fmt.Errorf("failed to count documents: %w", err)
@@ -151,3 +482,397 @@ func (m *MongoDBRepository) GetTotalProducts(ctx context.Context, domainName str
 	m.logger.Info("successfully count total products", "count", totalCount)
 	return int(totalCount), nil
 }
+
+// mongoProductFilter translates the filters of a query. Product fields are stored
+// under "data" with the driver's default lowercase names.
+func mongoProductFilter(query ports.ProductQuery) bson.M {
+	filter := bson.M{"domain": query.Domain}
+	if query.Status != "" {
+		filter["data.status"] = query.Status
+	}
+	if query.Tag != "" {
+		filter["data.tags"] = query.Tag
+	}
+	price := bson.M{}
+	if query.MinPrice != nil {
+		price["$gte"] = *query.MinPrice
+	}
+	if query.MaxPrice != nil {
+		price["$lte"] = *query.MaxPrice
+	}
+	if len(price) > 0 {
+		filter["data.price"] = price
+	}
+	if query.OnSale {
+		filter["discount"] = bson.M{"$gt": 0}
+	}
+	if !query.UpdatedSince.IsZero() {
+		filter["updated_at"] = bson.M{"$gte": query.UpdatedSince}
+	}
+	if !query.CreatedSince.IsZero() {
+		filter["created_at"] = bson.M{"$gte": query.CreatedSince}
+	}
+	crawled := bson.M{}
+	if !query.CrawledSince.IsZero() {
+		crawled["$gte"] = query.CrawledSince
+	}
+	if !query.CrawledBefore.IsZero() {
+		crawled["$lt"] = query.CrawledBefore
+	}
+	if len(crawled) > 0 {
+		filter["last_crawled_at"] = crawled
+	}
+	if query.CrawlRunID != "" {
+		filter["crawl_run_id"] = query.CrawlRunID
+	}
+	if query.Provider != "" {
+		filter["data.provider"] = query.Provider
+	}
+	if query.Search != "" {
+		filter["$text"] = bson.M{"$search": query.Search}
+	}
+	return filter
+}
+
+// mongoSortField is the document field behind a sort key, empty for insertion order
+func mongoSortField(key ports.ProductSortKey) string {
+	switch key {
+	case ports.SortPrice:
+		return "data.price"
+	case ports.SortName:
+		return "data.name"
+	case ports.SortUpdatedAt:
+		return "updated_at"
+	case ports.SortDiscount:
+		return "discount"
+	default:
+		return ""
+	}
+}
+
+// mongoProductSort orders by the query's sort key, breaking ties by _id so pages
+// do not overlap
+func mongoProductSort(query ports.ProductQuery) bson.D {
+	direction := 1
+	if query.Descending {
+		direction = -1
+	}
+	if field := mongoSortField(query.Sort); field != "" {
+		return bson.D{{Key: field, Value: direction}, {Key: "_id", Value: direction}}
+	}
+	return bson.D{{Key: "_id", Value: direction}}
+}
+
+// addMongoKeyset restricts filter to the documents sorted after the cursor
+func addMongoKeyset(filter bson.M, after *productCursor) error {
+	id, err := bson.ObjectIDFromHex(after.ID)
+	if err != nil {
+		return fmt.Errorf("%w: bad ID: %v", ports.ErrInvalidCursor, err)
+	}
+	value, err := after.sortValue()
+	if err != nil {
+		return err
+	}
+
+	operator := "$gt"
+	if after.Descending {
+		operator = "$lt"
+	}
+	field := mongoSortField(after.Sort)
+	if field == "" {
+		filter["_id"] = bson.M{operator: id}
+		return nil
+	}
+	filter["$or"] = bson.A{
+		bson.M{field: bson.M{operator: value}},
+		bson.M{field: value, "_id": bson.M{operator: id}},
+	}
+	return nil
+}
+
+// crawlDocument is how a CrawlRecord is stored
+type crawlDocument struct {
+	RunID         string    `bson:"run_id"`
+	Domain        string    `bson:"domain"`
+	Provider      string    `bson:"provider,omitempty"`
+	Status        string    `bson:"status"`
+	ProductsCount int       `bson:"products_count"`
+	Error         string    `bson:"error,omitempty"`
+	StartedAt     time.Time `bson:"started_at"`
+	FinishedAt    time.Time `bson:"finished_at"`
+}
+
+// RecordCrawl stores the outcome of a crawl
+func (m *MongoDBRepository) RecordCrawl(ctx context.Context, crawl ports.CrawlRecord) error {
+	m.logger.Info("recording crawl in MongoDB", "runID", crawl.RunID, "domain", crawl.Domain, "status", crawl.Status)
+
+	if _, err := m.crawls.InsertOne(ctx, crawlDocument(crawl)); err != nil {
+		m.logger.Error("failed to record crawl", "error", err)
+		return fmt.Errorf("failed to record crawl in MongoDB: %w", err)
+	}
+	return nil
+}
+
+// ListDomains returns a page of the domains with products or crawls, computed in
+// one aggregation: products are grouped by domain and status, crawl-only domains
+// are merged in with $unionWith and each domain's latest crawl is joined with
+// $lookup.
+func (m *MongoDBRepository) ListDomains(ctx context.Context, query ports.DomainQuery) (*ports.DomainPage, error) {
+	m.logger.Info("listing domains from MongoDB", "page", query.Page, "pageSize", query.PageSize, "sort", query.Sort)
+
+	direction := 1
+	if query.Descending {
+		direction = -1
+	}
+	var sort bson.D
+	switch query.Sort {
+	case ports.SortProductCount:
+		sort = bson.D{{Key: "product_count", Value: direction}, {Key: "_id", Value: direction}}
+	case ports.SortLastCrawledAt:
+		// Never crawled domains last in either direction
+		sort = bson.D{{Key: "crawled", Value: -1}, {Key: "last_crawl.finished_at", Value: direction}, {Key: "_id", Value: direction}}
+	default:
+		sort = bson.D{{Key: "_id", Value: direction}}
+	}
+
+	pipeline := bson.A{
+		bson.M{"$group": bson.M{
+			"_id":      bson.M{"domain": "$domain", "status": "$data.status"},
+			"count":    bson.M{"$sum": 1},
+			"provider": bson.M{"$max": "$data.provider"},
+		}},
+		bson.M{"$group": bson.M{
+			"_id":           "$_id.domain",
+			"product_count": bson.M{"$sum": "$count"},
+			"statuses":      bson.M{"$push": bson.M{"status": "$_id.status", "count": "$count"}},
+			"provider":      bson.M{"$max": "$provider"},
+		}},
+		bson.M{"$unionWith": bson.M{
+			"coll":     m.crawls.Name(),
+			"pipeline": bson.A{bson.M{"$group": bson.M{"_id": "$domain"}}},
+		}},
+		bson.M{"$group": bson.M{
+			"_id":           "$_id",
+			"product_count": bson.M{"$sum": "$product_count"},
+			"statuses":      bson.M{"$max": "$statuses"},
+			"provider":      bson.M{"$max": "$provider"},
+		}},
+		bson.M{"$lookup": bson.M{
+			"from": m.crawls.Name(),
+			"let":  bson.M{"domain": "$_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$domain", "$$domain"}}}},
+				bson.M{"$sort": bson.M{"finished_at": -1}},
+				bson.M{"$limit": 1},
+			},
+			"as": "last_crawl",
+		}},
+		bson.M{"$set": bson.M{
+			"last_crawl": bson.M{"$arrayElemAt": bson.A{"$last_crawl", 0}},
+			"crawled":    bson.M{"$gt": bson.A{bson.M{"$size": "$last_crawl"}, 0}},
+		}},
+		bson.M{"$facet": bson.M{
+			"total":   bson.A{bson.M{"$count": "count"}},
+			"domains": bson.A{bson.M{"$sort": sort}, bson.M{"$skip": query.Offset()}, bson.M{"$limit": query.PageSize}},
+		}},
+	}
+
+	cursor, err := m.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		m.logger.Error("failed to execute aggregation", "error", err)
+		return nil, fmt.Errorf("failed to execute aggregation: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Total []struct {
+			Count int `bson:"count"`
+		} `bson:"total"`
+		Domains []struct {
+			Domain       string `bson:"_id"`
+			ProductCount int    `bson:"product_count"`
+			Provider     string `bson:"provider"`
+			Statuses     []struct {
+				Status string `bson:"status"`
+				Count  int    `bson:"count"`
+			} `bson:"statuses"`
+			LastCrawl *crawlDocument `bson:"last_crawl"`
+		} `bson:"domains"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		m.logger.Error("failed to decode domains", "error", err)
+		return nil, fmt.Errorf("failed to decode domains: %w", err)
+	}
+
+	page := &ports.DomainPage{Domains: make([]*ports.DomainSummary, 0)}
+	if len(result) == 0 {
+		return page, nil
+	}
+	if len(result[0].Total) > 0 {
+		page.TotalItems = result[0].Total[0].Count
+	}
+	for _, row := range result[0].Domains {
+		summary := &ports.DomainSummary{
+			Domain:       row.Domain,
+			Provider:     row.Provider,
+			ProductCount: row.ProductCount,
+			StatusCounts: make(map[string]int, len(row.Statuses)),
+		}
+		for _, status := range row.Statuses {
+			summary.StatusCounts[status.Status] = status.Count
+		}
+		if row.LastCrawl != nil {
+			crawl := ports.CrawlRecord(*row.LastCrawl)
+			summary.LastCrawl = &crawl
+			if summary.Provider == "" {
+				summary.Provider = crawl.Provider
+			}
+		}
+		page.Domains = append(page.Domains, summary)
+	}
+
+	m.logger.Info("successfully listed domains", "count", len(page.Domains), "total", page.TotalItems)
+	return page, nil
+}
+
+// GetDomainStats summarises the products of a domain with two aggregations: a
+// $facet computing the totals, averages, median and top tags in one pass, then
+// a $bucket over the price range it found
+func (m *MongoDBRepository) GetDomainStats(ctx context.Context, domainName string) (*ports.DomainStats, error) {
+	m.logger.Info("getting domain stats from MongoDB", "domain", domainName)
+
+	match := bson.M{"$match": bson.M{"domain": domainName}}
+	pipeline := bson.A{
+		match,
+		bson.M{"$facet": bson.M{
+			"summary": bson.A{bson.M{"$group": bson.M{
+				"_id":      nil,
+				"count":    bson.M{"$sum": 1},
+				"in_stock": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$ne": bson.A{"$data.status", domain.StatusOutOfStock}}, 1, 0}}},
+				"min":      bson.M{"$min": "$data.price"},
+				"max":      bson.M{"$max": "$data.price"},
+				"on_sale":  bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$discount", 0}}, 1, 0}}},
+				// $avg skips the nulls of products not on sale
+				"discount": bson.M{"$avg": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$discount", 0}}, "$discount", nil}}},
+				"images":   bson.M{"$avg": bson.M{"$size": bson.M{"$ifNull": bson.A{"$data.imagesurl", bson.A{}}}}},
+			}}},
+			"median": bson.A{
+				bson.M{"$sort": bson.M{"data.price": 1}},
+				bson.M{"$group": bson.M{"_id": nil, "prices": bson.M{"$push": "$data.price"}}},
+				bson.M{"$project": bson.M{"median": bson.M{"$let": bson.M{
+					"vars": bson.M{"last": bson.M{"$subtract": bson.A{bson.M{"$size": "$prices"}, 1}}},
+					"in": bson.M{"$avg": bson.A{
+						bson.M{"$arrayElemAt": bson.A{"$prices", bson.M{"$toInt": bson.M{"$floor": bson.M{"$divide": bson.A{"$$last", 2}}}}}},
+						bson.M{"$arrayElemAt": bson.A{"$prices", bson.M{"$toInt": bson.M{"$ceil": bson.M{"$divide": bson.A{"$$last", 2}}}}}},
+					}},
+				}}}},
+			},
+			"tags": bson.A{
+				bson.M{"$unwind": "$data.tags"},
+				bson.M{"$group": bson.M{"_id": "$data.tags", "count": bson.M{"$sum": 1}}},
+				bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+				bson.M{"$limit": ports.DomainStatsTopTags},
+			},
+		}},
+	}
+
+	cursor, err := m.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		m.logger.Error("failed to execute aggregation", "error", err)
+		return nil, fmt.Errorf("failed to execute aggregation: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Summary []struct {
+			Count    int      `bson:"count"`
+			InStock  int      `bson:"in_stock"`
+			Min      int      `bson:"min"`
+			Max      int      `bson:"max"`
+			OnSale   int      `bson:"on_sale"`
+			Discount *float64 `bson:"discount"`
+			Images   float64  `bson:"images"`
+		} `bson:"summary"`
+		Median []struct {
+			Median float64 `bson:"median"`
+		} `bson:"median"`
+		Tags []struct {
+			Tag   string `bson:"_id"`
+			Count int    `bson:"count"`
+		} `bson:"tags"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		m.logger.Error("failed to decode domain stats", "error", err)
+		return nil, fmt.Errorf("failed to decode domain stats: %w", err)
+	}
+
+	stats := &ports.DomainStats{Domain: domainName, TopTags: make([]ports.TagCount, 0)}
+	if len(result) == 0 || len(result[0].Summary) == 0 {
+		return stats, nil
+	}
+	summary := result[0].Summary[0]
+	stats.ProductCount = summary.Count
+	stats.InStockCount = summary.InStock
+	stats.InStockRatio = float64(summary.InStock) / float64(summary.Count)
+	stats.OnSaleCount = summary.OnSale
+	if summary.Discount != nil {
+		stats.AverageDiscount = *summary.Discount
+	}
+	stats.ImagesPerProduct = summary.Images
+	stats.Price = ports.PriceStats{Min: summary.Min, Max: summary.Max}
+	if len(result[0].Median) > 0 {
+		stats.Price.Median = result[0].Median[0].Median
+	}
+	for _, tag := range result[0].Tags {
+		stats.TopTags = append(stats.TopTags, ports.TagCount{Tag: tag.Tag, Count: tag.Count})
+	}
+
+	if stats.Price.Buckets, err = m.priceBuckets(ctx, domainName, summary.Min, summary.Max); err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("successfully computed domain stats", "domain", domainName, "products", stats.ProductCount)
+	return stats, nil
+}
+
+// priceBuckets counts the domain's products in the buckets of ports.NewPriceBuckets
+func (m *MongoDBRepository) priceBuckets(ctx context.Context, domainName string, low, high int) ([]ports.PriceBucket, error) {
+	buckets := ports.NewPriceBuckets(low, high)
+	boundaries := bson.A{}
+	for _, bucket := range buckets {
+		boundaries = append(boundaries, bucket.Min)
+	}
+	boundaries = append(boundaries, buckets[len(buckets)-1].Max)
+
+	pipeline := bson.A{
+		// Products saved since the range was read may fall outside it
+		bson.M{"$match": bson.M{"domain": domainName, "data.price": bson.M{"$gte": low, "$lt": buckets[len(buckets)-1].Max}}},
+		bson.M{"$bucket": bson.M{"groupBy": "$data.price", "boundaries": boundaries}},
+	}
+
+	cursor, err := m.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		m.logger.Error("failed to execute aggregation", "error", err)
+		return nil, fmt.Errorf("failed to execute aggregation: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var counts []struct {
+		Min   int `bson:"_id"`
+		Count int `bson:"count"`
+	}
+	if err := cursor.All(ctx, &counts); err != nil {
+		m.logger.Error("failed to decode price buckets", "error", err)
+		return nil, fmt.Errorf("failed to decode price buckets: %w", err)
+	}
+
+	// $bucket leaves out empty buckets
+	width := buckets[0].Max - buckets[0].Min
+	for _, count := range counts {
+		buckets[(count.Min-low)/width].Count = count.Count
+	}
+	return buckets, nil
+}
+
+// Ensure MongoDBRepository implements ProductRepository
+var _ ports.ProductRepository = (*MongoDBRepository)(nil)
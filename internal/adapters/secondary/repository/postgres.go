@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // Registers the "pgx" database/sql driver
+	"web-crawler-go/internal/core/domain"
+	"web-crawler-go/internal/core/ports"
+)
+
+// PostgresRepository implements the ProductRepository interface on PostgreSQL.
+// Image URLs, tags and variants are stored as JSONB.
+type PostgresRepository struct {
+	db     *sql.DB
+	logger ports.Logger
+}
+
+// postgresUpsertQuery inserts a product or updates the one with the same domain and name
+const postgresUpsertQuery = `
+	INSERT INTO products (domain, name, price, price_discounted, description, images_url, tags, status, source_url, provider,
+		crawl_run_id, last_crawled_at, variants)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, CASE WHEN $11 <> '' THEN now() END, $12)
+	ON CONFLICT (domain, name) DO UPDATE SET
+		price            = EXCLUDED.price,
+		price_discounted = EXCLUDED.price_discounted,
+		description      = EXCLUDED.description,
+		images_url       = EXCLUDED.images_url,
+		tags             = EXCLUDED.tags,
+		variants         = EXCLUDED.variants,
+		status           = EXCLUDED.status,
+		source_url       = EXCLUDED.source_url,
+		provider         = EXCLUDED.provider,
+		updated_at       = now(),
+		-- Saves outside a crawl keep the last crawl
+		crawl_run_id     = COALESCE(NULLIF(EXCLUDED.crawl_run_id, ''), products.crawl_run_id),
+		last_crawled_at  = COALESCE(EXCLUDED.last_crawled_at, products.last_crawled_at)`
+
+// NewPostgresRepository connects to PostgreSQL and applies pending schema migrations
+func NewPostgresRepository(ctx context.Context, dsn string, logger ports.Logger) (*PostgresRepository, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PostgreSQL connection: %w", err)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping PostgreSQL: %w", err)
+	}
+
+	if err := runMigrations(ctx, db, "postgres", "$1", logger); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	logger.Info("connected to PostgreSQL")
+
+	return &PostgresRepository{
+		db:     db,
+		logger: logger,
+	}, nil
+}
+
+// UpsertProduct inserts a product or updates the one with the same domain and name
+func (r *PostgresRepository) UpsertProduct(ctx context.Context, product *domain.Product) error {
+	r.logger.Info("upserting product to PostgreSQL", "name", product.Name)
+
+	args, err := upsertArgs(product)
+	if err != nil {
+		return err
+	}
+
+	var inserted bool
+	err = r.db.QueryRowContext(ctx, postgresUpsertQuery+" RETURNING (xmax = 0)", args...).Scan(&inserted)
+	if err != nil {
+		r.logger.Error("failed to upsert product to PostgreSQL", "error", err)
+		return fmt.Errorf("failed to upsert product to PostgreSQL: %w", err)
+	}
+
+	if inserted {
+		r.logger.Info("product inserted to PostgreSQL", "name", product.Name)
+	} else {
+		r.logger.Info("product updated in PostgreSQL", "name", product.Name)
+	}
+
+	return nil
+}
+
+// UpsertProducts saves a batch, one transaction per chunk
+func (r *PostgresRepository) UpsertProducts(ctx context.Context, products []*domain.Product) (int, error) {
+	r.logger.Info("upserting products to PostgreSQL", "count", len(products))
+
+	saved, err := upsertProductsSQL(ctx, r.db, postgresUpsertQuery, products)
+	if err != nil {
+		r.logger.Error("failed to upsert products to PostgreSQL", "saved", saved, "error", err)
+		return saved, err
+	}
+
+	r.logger.Info("products upserted to PostgreSQL", "count", saved)
+	return saved, nil
+}
+
+// Close closes the PostgreSQL connection pool
+func (r *PostgresRepository) Close(ctx context.Context) error {
+	r.logger.Info("closing PostgreSQL connection")
+	if err := r.db.Close(); err != nil {
+		r.logger.Error("failed to close PostgreSQL connection", "error", err)
+		return fmt.Errorf("failed to close PostgreSQL connection: %w", err)
+	}
+	return nil
+}
+
+// postgresDialect matches tags with JSONB containment and searches words with
+// full-text search
+var postgresDialect = sqlDialect{
+	bind:         func(n int) string { return "$" + strconv.Itoa(n) },
+	tagCondition: func(p string) string { return "tags @> " + p + "::jsonb" },
+	tagArg: func(tag string) any {
+		encoded, _ := json.Marshal([]string{tag})
+		return string(encoded)
+	},
+	searchCondition: func(p string) string {
+		return "to_tsvector('simple', name || ' ' || description) @@ plainto_tsquery('simple', " + p + ")"
+	},
+	searchArg:   func(term string) any { return term },
+	timeArg:     func(t time.Time) any { return t },
+	tagElements: "jsonb_array_elements_text(products.tags) AS t(value)",
+	imageCount:  "jsonb_array_length(images_url)",
+}
+
+// GetProducts returns a page of the products matching the query
+func (r *PostgresRepository) GetProducts(ctx context.Context, query ports.ProductQuery) (*ports.ProductPage, error) {
+	r.logger.Info("getting products from PostgreSQL", "domainName", query.Domain, "page", query.Page, "pageSize", query.PageSize, "sort", query.Sort, "cursor", query.Cursor != "")
+
+	page, err := postgresDialect.queryProducts(ctx, r.db, query)
+	if err != nil {
+		r.logger.Error("failed to query products", "error", err)
+		return nil, err
+	}
+	r.logger.Info("successfully fetched products", "count", len(page.Products))
+	return page, nil
+}
+
+// GetProduct returns the product with the ID
+func (r *PostgresRepository) GetProduct(ctx context.Context, id string) (*domain.Product, error) {
+	r.logger.Info("getting product from PostgreSQL", "id", id)
+
+	product, err := postgresDialect.getProduct(ctx, r.db, id)
+	if err != nil && !errors.Is(err, ports.ErrProductNotFound) {
+		r.logger.Error("failed to get product", "id", id, "error", err)
+	}
+	return product, err
+}
+
+// GetTotalProducts counts the products matching the query
+func (r *PostgresRepository) GetTotalProducts(ctx context.Context, query ports.ProductQuery) (int, error) {
+	r.logger.Info("getting total products from PostgreSQL", "domainName", query.Domain)
+
+	total, err := postgresDialect.countProducts(ctx, r.db, query)
+	if err != nil {
+		r.logger.Error("failed to count products", "error", err)
+		return 0, err
+	}
+	r.logger.Info("successfully count total products", "count", total)
+	return total, nil
+}
+
+// RecordCrawl stores the outcome of a crawl
+func (r *PostgresRepository) RecordCrawl(ctx context.Context, crawl ports.CrawlRecord) error {
+	r.logger.Info("recording crawl in PostgreSQL", "runID", crawl.RunID, "domain", crawl.Domain, "status", crawl.Status)
+
+	if err := postgresDialect.recordCrawl(ctx, r.db, crawl); err != nil {
+		r.logger.Error("failed to record crawl", "error", err)
+		return err
+	}
+	return nil
+}
+
+// ListDomains returns a page of the domains with products or crawls
+func (r *PostgresRepository) ListDomains(ctx context.Context, query ports.DomainQuery) (*ports.DomainPage, error) {
+	r.logger.Info("listing domains from PostgreSQL", "page", query.Page, "pageSize", query.PageSize, "sort", query.Sort)
+
+	page, err := postgresDialect.listDomains(ctx, r.db, query)
+	if err != nil {
+		r.logger.Error("failed to list domains", "error", err)
+		return nil, err
+	}
+	r.logger.Info("successfully listed domains", "count", len(page.Domains), "total", page.TotalItems)
+	return page, nil
+}
+
+// GetDomainStats summarises the products of a domain
+func (r *PostgresRepository) GetDomainStats(ctx context.Context, domainName string) (*ports.DomainStats, error) {
+	r.logger.Info("getting domain stats from PostgreSQL", "domain", domainName)
+
+	stats, err := postgresDialect.domainStats(ctx, r.db, domainName)
+	if err != nil {
+		r.logger.Error("failed to get domain stats", "domain", domainName, "error", err)
+		return nil, err
+	}
+	r.logger.Info("successfully computed domain stats", "domain", domainName, "products", stats.ProductCount)
+	return stats, nil
+}
+
+// Ensure PostgresRepository implements ProductRepository
+var _ ports.ProductRepository = (*PostgresRepository)(nil)
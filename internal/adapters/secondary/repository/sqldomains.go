@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"web-crawler-go/internal/core/domain"
+	"web-crawler-go/internal/core/ports"
+)
+
+// Domain catalogue and statistics queries shared by the database/sql repositories
+
+// crawlColumns are the columns of a CrawlRecord in crawls
+const crawlColumns = "run_id, domain, provider, status, products_count, error, started_at, finished_at"
+
+// recordCrawl inserts a row into crawls
+func (d sqlDialect) recordCrawl(ctx context.Context, db *sql.DB, crawl ports.CrawlRecord) error {
+	args := &sqlArgs{bind: d.bind}
+	values := []string{
+		args.add(crawl.RunID), args.add(crawl.Domain), args.add(crawl.Provider), args.add(crawl.Status),
+		args.add(crawl.ProductsCount), args.add(crawl.Error),
+		args.add(d.timeArg(crawl.StartedAt)), args.add(d.timeArg(crawl.FinishedAt)),
+	}
+	statement := "INSERT INTO crawls (" + crawlColumns + ") VALUES (" + strings.Join(values, ", ") + ")"
+
+	if _, err := db.ExecContext(ctx, statement, args.values...); err != nil {
+		return fmt.Errorf("failed to record crawl: %w", err)
+	}
+	return nil
+}
+
+// domainOrder builds the ORDER BY clause of the domain listing over the columns
+// selected by listDomains, breaking ties by domain
+func domainOrder(query ports.DomainQuery) string {
+	direction := "ASC"
+	if query.Descending {
+		direction = "DESC"
+	}
+	switch query.Sort {
+	case ports.SortProductCount:
+		return "product_count " + direction + ", domain " + direction
+	case ports.SortLastCrawledAt:
+		// Never crawled domains last in either direction
+		return "(last_crawled_at IS NULL), last_crawled_at " + direction + ", domain " + direction
+	default:
+		return "domain " + direction
+	}
+}
+
+// listDomains selects a page of the domains with products or crawls. Status
+// counts and latest crawls are read for the page's domains only.
+func (d sqlDialect) listDomains(ctx context.Context, db *sql.DB, query ports.DomainQuery) (*ports.DomainPage, error) {
+	page := &ports.DomainPage{Domains: make([]*ports.DomainSummary, 0)}
+
+	const domains = "(SELECT domain FROM products UNION SELECT domain FROM crawls) d"
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+domains).Scan(&page.TotalItems); err != nil {
+		return nil, fmt.Errorf("failed to count domains: %w", err)
+	}
+
+	args := &sqlArgs{bind: d.bind}
+	statement := "SELECT domain, product_count, provider FROM (SELECT d.domain," +
+		" (SELECT COUNT(*) FROM products p WHERE p.domain = d.domain) AS product_count," +
+		" COALESCE((SELECT MAX(p.provider) FROM products p WHERE p.domain = d.domain), '') AS provider," +
+		" (SELECT MAX(c.finished_at) FROM crawls c WHERE c.domain = d.domain) AS last_crawled_at" +
+		" FROM " + domains + ") s" +
+		" ORDER BY " + domainOrder(query) +
+		" LIMIT " + args.add(query.PageSize) + " OFFSET " + args.add(query.Offset())
+
+	rows, err := db.QueryContext(ctx, statement, args.values...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query domains: %w", err)
+	}
+	defer rows.Close()
+
+	byDomain := make(map[string]*ports.DomainSummary)
+	for rows.Next() {
+		summary := &ports.DomainSummary{StatusCounts: make(map[string]int)}
+		if err := rows.Scan(&summary.Domain, &summary.ProductCount, &summary.Provider); err != nil {
+			return nil, fmt.Errorf("failed to scan domain: %w", err)
+		}
+		page.Domains = append(page.Domains, summary)
+		byDomain[summary.Domain] = summary
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate domains: %w", err)
+	}
+	if len(page.Domains) == 0 {
+		return page, nil
+	}
+
+	if err := d.addStatusCounts(ctx, db, byDomain); err != nil {
+		return nil, err
+	}
+	if err := d.addLastCrawls(ctx, db, byDomain); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// domainIn returns an IN condition on the column matching the domains, adding
+// its arguments
+func domainIn(column string, byDomain map[string]*ports.DomainSummary, args *sqlArgs) string {
+	placeholders := make([]string, 0, len(byDomain))
+	for name := range byDomain {
+		placeholders = append(placeholders, args.add(name))
+	}
+	return column + " IN (" + strings.Join(placeholders, ", ") + ")"
+}
+
+// addStatusCounts fills in the product counts by status of the domains
+func (d sqlDialect) addStatusCounts(ctx context.Context, db *sql.DB, byDomain map[string]*ports.DomainSummary) error {
+	args := &sqlArgs{bind: d.bind}
+	statement := "SELECT domain, status, COUNT(*) FROM products WHERE " + domainIn("domain", byDomain, args) +
+		" GROUP BY domain, status"
+
+	rows, err := db.QueryContext(ctx, statement, args.values...)
+	if err != nil {
+		return fmt.Errorf("failed to count products by status: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, status string
+		var count int
+		if err := rows.Scan(&name, &status, &count); err != nil {
+			return fmt.Errorf("failed to scan status count: %w", err)
+		}
+		byDomain[name].StatusCounts[status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate status counts: %w", err)
+	}
+	return nil
+}
+
+// addLastCrawls fills in the latest crawl of the domains, falling back to its
+// provider for domains without products
+func (d sqlDialect) addLastCrawls(ctx context.Context, db *sql.DB, byDomain map[string]*ports.DomainSummary) error {
+	args := &sqlArgs{bind: d.bind}
+	statement := "SELECT " + crawlColumns + " FROM crawls c WHERE " + domainIn("c.domain", byDomain, args) +
+		" AND c.id = (SELECT l.id FROM crawls l WHERE l.domain = c.domain ORDER BY l.finished_at DESC, l.id DESC LIMIT 1)"
+
+	rows, err := db.QueryContext(ctx, statement, args.values...)
+	if err != nil {
+		return fmt.Errorf("failed to query latest crawls: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var crawl ports.CrawlRecord
+		var startedAt, finishedAt sqlTime
+		err := rows.Scan(&crawl.RunID, &crawl.Domain, &crawl.Provider, &crawl.Status,
+			&crawl.ProductsCount, &crawl.Error, &startedAt, &finishedAt)
+		if err != nil {
+			return fmt.Errorf("failed to scan crawl: %w", err)
+		}
+		crawl.StartedAt, crawl.FinishedAt = time.Time(startedAt), time.Time(finishedAt)
+
+		summary := byDomain[crawl.Domain]
+		summary.LastCrawl = &crawl
+		if summary.Provider == "" {
+			summary.Provider = crawl.Provider
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate latest crawls: %w", err)
+	}
+	return nil
+}
+
+// domainStats summarises the products of a domain in four queries: totals and
+// averages, the median, the price buckets and the top tags
+func (d sqlDialect) domainStats(ctx context.Context, db *sql.DB, domainName string) (*ports.DomainStats, error) {
+	stats := &ports.DomainStats{Domain: domainName, TopTags: make([]ports.TagCount, 0)}
+
+	args := &sqlArgs{bind: d.bind}
+	statement := "SELECT COUNT(*)," +
+		" COALESCE(SUM(CASE WHEN status <> " + args.add(domain.StatusOutOfStock) + " THEN 1 ELSE 0 END), 0)," +
+		" COALESCE(MIN(price), 0), COALESCE(MAX(price), 0)," +
+		" COALESCE(SUM(CASE WHEN discount_percent > 0 THEN 1 ELSE 0 END), 0)," +
+		" COALESCE(CAST(AVG(CASE WHEN discount_percent > 0 THEN discount_percent END) AS DOUBLE PRECISION), 0)," +
+		" COALESCE(CAST(AVG(" + d.imageCount + ") AS DOUBLE PRECISION), 0)" +
+		" FROM products WHERE domain = " + args.add(domainName)
+	err := db.QueryRowContext(ctx, statement, args.values...).Scan(&stats.ProductCount, &stats.InStockCount,
+		&stats.Price.Min, &stats.Price.Max, &stats.OnSaleCount, &stats.AverageDiscount, &stats.ImagesPerProduct)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarise products: %w", err)
+	}
+	if stats.ProductCount == 0 {
+		return stats, nil
+	}
+	stats.InStockRatio = float64(stats.InStockCount) / float64(stats.ProductCount)
+
+	if stats.Price.Median, err = d.medianPrice(ctx, db, domainName, stats.ProductCount); err != nil {
+		return nil, err
+	}
+	if stats.Price.Buckets, err = d.priceBuckets(ctx, db, domainName, stats.Price.Min, stats.Price.Max); err != nil {
+		return nil, err
+	}
+	if stats.TopTags, err = d.topTags(ctx, db, domainName); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// medianPrice averages the one or two middle prices of the domain's count products
+func (d sqlDialect) medianPrice(ctx context.Context, db *sql.DB, domainName string, count int) (float64, error) {
+	args := &sqlArgs{bind: d.bind}
+	statement := "SELECT price FROM products WHERE domain = " + args.add(domainName) +
+		" ORDER BY price LIMIT " + args.add(2-count%2) + " OFFSET " + args.add((count-1)/2)
+
+	rows, err := db.QueryContext(ctx, statement, args.values...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query median price: %w", err)
+	}
+	defer rows.Close()
+
+	sum, n := 0, 0
+	for rows.Next() {
+		var price int
+		if err := rows.Scan(&price); err != nil {
+			return 0, fmt.Errorf("failed to scan price: %w", err)
+		}
+		sum += price
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to iterate prices: %w", err)
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return float64(sum) / float64(n), nil
+}
+
+// priceBuckets counts the domain's products in the buckets of ports.NewPriceBuckets
+func (d sqlDialect) priceBuckets(ctx context.Context, db *sql.DB, domainName string, low, high int) ([]ports.PriceBucket, error) {
+	buckets := ports.NewPriceBuckets(low, high)
+	width := buckets[0].Max - buckets[0].Min
+
+	args := &sqlArgs{bind: d.bind}
+	statement := "SELECT (price - " + args.add(low) + ") / " + args.add(width) + " AS bucket, COUNT(*) FROM products" +
+		" WHERE domain = " + args.add(domainName) +
+		" AND price >= " + args.add(low) + " AND price < " + args.add(buckets[len(buckets)-1].Max) +
+		" GROUP BY bucket"
+
+	rows, err := db.QueryContext(ctx, statement, args.values...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count products by price: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket, count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan price bucket: %w", err)
+		}
+		buckets[bucket].Count = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate price buckets: %w", err)
+	}
+	return buckets, nil
+}
+
+// topTags returns the domain's most used tags, most used first
+func (d sqlDialect) topTags(ctx context.Context, db *sql.DB, domainName string) ([]ports.TagCount, error) {
+	args := &sqlArgs{bind: d.bind}
+	statement := "SELECT t.value, COUNT(*) AS uses FROM products, " + d.tagElements +
+		" WHERE products.domain = " + args.add(domainName) +
+		" GROUP BY t.value ORDER BY uses DESC, t.value LIMIT " + args.add(ports.DomainStatsTopTags)
+
+	rows, err := db.QueryContext(ctx, statement, args.values...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make([]ports.TagCount, 0)
+	for rows.Next() {
+		var tag ports.TagCount
+		if err := rows.Scan(&tag.Tag, &tag.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan tag count: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate tag counts: %w", err)
+	}
+	return tags, nil
+}
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // Registers the pure-Go "sqlite" database/sql driver
+	"web-crawler-go/internal/core/domain"
+	"web-crawler-go/internal/core/ports"
+)
+
+// SQLiteRepository implements the ProductRepository interface on an embedded
+// SQLite database file, so the crawler can run without a database server.
+// The database runs in WAL mode so reads are not blocked by a crawl's writes.
+type SQLiteRepository struct {
+	db     *sql.DB
+	logger ports.Logger
+}
+
+// NewSQLiteRepository opens (creating if needed) the database file at path and
+// applies pending schema migrations
+func NewSQLiteRepository(ctx context.Context, path string, logger ports.Logger) (*SQLiteRepository, error) {
+	pragmas := url.Values{}
+	pragmas.Add("_pragma", "journal_mode(WAL)")
+	pragmas.Add("_pragma", "synchronous(NORMAL)")
+	// Wait for the writer instead of failing with SQLITE_BUSY
+	pragmas.Add("_pragma", "busy_timeout(5000)")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+pragmas.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open SQLite database %s: %w", path, err)
+	}
+
+	if err := runMigrations(ctx, db, "sqlite", "?", logger); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	logger.Info("opened SQLite database", "path", path)
+
+	return &SQLiteRepository{
+		db:     db,
+		logger: logger,
+	}, nil
+}
+
+// sqliteUpsertQuery inserts a product or updates the one with the same domain and name
+const sqliteUpsertQuery = `
+	INSERT INTO products (domain, name, price, price_discounted, description, images_url, tags, status, source_url, provider,
+		crawl_run_id, last_crawled_at, variants)
+	VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, CASE WHEN ?11 <> '' THEN strftime('%Y-%m-%dT%H:%M:%fZ', 'now') END, ?12)
+	ON CONFLICT (domain, name) DO UPDATE SET
+		price            = excluded.price,
+		price_discounted = excluded.price_discounted,
+		description      = excluded.description,
+		images_url       = excluded.images_url,
+		tags             = excluded.tags,
+		variants         = excluded.variants,
+		status           = excluded.status,
+		source_url       = excluded.source_url,
+		provider         = excluded.provider,
+		updated_at       = strftime('%Y-%m-%dT%H:%M:%fZ', 'now'),
+		-- Saves outside a crawl keep the last crawl
+		crawl_run_id     = COALESCE(NULLIF(excluded.crawl_run_id, ''), products.crawl_run_id),
+		last_crawled_at  = COALESCE(excluded.last_crawled_at, products.last_crawled_at)`
+
+// UpsertProduct inserts a product or updates the one with the same domain and name
+func (r *SQLiteRepository) UpsertProduct(ctx context.Context, product *domain.Product) error {
+	r.logger.Info("upserting product to SQLite", "name", product.Name)
+
+	args, err := upsertArgs(product)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, sqliteUpsertQuery, args...)
+	if err != nil {
+		r.logger.Error("failed to upsert product to SQLite", "error", err)
+		return fmt.Errorf("failed to upsert product to SQLite: %w", err)
+	}
+
+	return nil
+}
+
+// UpsertProducts saves a batch, one transaction per chunk
+func (r *SQLiteRepository) UpsertProducts(ctx context.Context, products []*domain.Product) (int, error) {
+	r.logger.Info("upserting products to SQLite", "count", len(products))
+
+	saved, err := upsertProductsSQL(ctx, r.db, sqliteUpsertQuery, products)
+	if err != nil {
+		r.logger.Error("failed to upsert products to SQLite", "saved", saved, "error", err)
+		return saved, err
+	}
+
+	r.logger.Info("products upserted to SQLite", "count", saved)
+	return saved, nil
+}
+
+// Close closes the database
+func (r *SQLiteRepository) Close(ctx context.Context) error {
+	r.logger.Info("closing SQLite database")
+	if err := r.db.Close(); err != nil {
+		r.logger.Error("failed to close SQLite database", "error", err)
+		return fmt.Errorf("failed to close SQLite database: %w", err)
+	}
+	return nil
+}
+
+// sqliteTimeLayout matches how the schema writes timestamps, so they compare as text
+const sqliteTimeLayout = "2006-01-02T15:04:05.000Z"
+
+// sqliteDialect matches tags through json_each and searches with a substring LIKE,
+// which ignores case for ASCII letters
+var sqliteDialect = sqlDialect{
+	bind: func(int) string { return "?" },
+	tagCondition: func(p string) string {
+		return "EXISTS (SELECT 1 FROM json_each(products.tags) WHERE json_each.value = " + p + ")"
+	},
+	tagArg: func(tag string) any { return tag },
+	searchCondition: func(p string) string {
+		return "(name || ' ' || description) LIKE " + p + ` ESCAPE '\'`
+	},
+	searchArg: func(term string) any {
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(term)
+		return "%" + escaped + "%"
+	},
+	timeArg:     func(t time.Time) any { return t.UTC().Format(sqliteTimeLayout) },
+	tagElements: "json_each(products.tags) AS t",
+	imageCount:  "json_array_length(images_url)",
+}
+
+// GetProducts returns a page of the products matching the query
+func (r *SQLiteRepository) GetProducts(ctx context.Context, query ports.ProductQuery) (*ports.ProductPage, error) {
+	r.logger.Info("getting products from SQLite", "domainName", query.Domain, "page", query.Page, "pageSize", query.PageSize, "sort", query.Sort, "cursor", query.Cursor != "")
+
+	page, err := sqliteDialect.queryProducts(ctx, r.db, query)
+	if err != nil {
+		r.logger.Error("failed to query products", "error", err)
+		return nil, err
+	}
+	r.logger.Info("successfully fetched products", "count", len(page.Products))
+	return page, nil
+}
+
+// GetProduct returns the product with the ID
+func (r *SQLiteRepository) GetProduct(ctx context.Context, id string) (*domain.Product, error) {
+	r.logger.Info("getting product from SQLite", "id", id)
+
+	product, err := sqliteDialect.getProduct(ctx, r.db, id)
+	if err != nil && !errors.Is(err, ports.ErrProductNotFound) {
+		r.logger.Error("failed to get product", "id", id, "error", err)
+	}
+	return product, err
+}
+
+// GetTotalProducts counts the products matching the query
+func (r *SQLiteRepository) GetTotalProducts(ctx context.Context, query ports.ProductQuery) (int, error) {
+	r.logger.Info("getting total products from SQLite", "domainName", query.Domain)
+
+	total, err := sqliteDialect.countProducts(ctx, r.db, query)
+	if err != nil {
+		r.logger.Error("failed to count products", "error", err)
+		return 0, err
+	}
+	r.logger.Info("successfully count total products", "count", total)
+	return total, nil
+}
+
+// RecordCrawl stores the outcome of a crawl
+func (r *SQLiteRepository) RecordCrawl(ctx context.Context, crawl ports.CrawlRecord) error {
+	r.logger.Info("recording crawl in SQLite", "runID", crawl.RunID, "domain", crawl.Domain, "status", crawl.Status)
+
+	if err := sqliteDialect.recordCrawl(ctx, r.db, crawl); err != nil {
+		r.logger.Error("failed to record crawl", "error", err)
+		return err
+	}
+	return nil
+}
+
+// ListDomains returns a page of the domains with products or crawls
+func (r *SQLiteRepository) ListDomains(ctx context.Context, query ports.DomainQuery) (*ports.DomainPage, error) {
+	r.logger.Info("listing domains from SQLite", "page", query.Page, "pageSize", query.PageSize, "sort", query.Sort)
+
+	page, err := sqliteDialect.listDomains(ctx, r.db, query)
+	if err != nil {
+		r.logger.Error("failed to list domains", "error", err)
+		return nil, err
+	}
+	r.logger.Info("successfully listed domains", "count", len(page.Domains), "total", page.TotalItems)
+	return page, nil
+}
+
+// GetDomainStats summarises the products of a domain
+func (r *SQLiteRepository) GetDomainStats(ctx context.Context, domainName string) (*ports.DomainStats, error) {
+	r.logger.Info("getting domain stats from SQLite", "domain", domainName)
+
+	stats, err := sqliteDialect.domainStats(ctx, r.db, domainName)
+	if err != nil {
+		r.logger.Error("failed to get domain stats", "domain", domainName, "error", err)
+		return nil, err
+	}
+	r.logger.Info("successfully computed domain stats", "domain", domainName, "products", stats.ProductCount)
+	return stats, nil
+}
+
+// Ensure SQLiteRepository implements ProductRepository
+var _ ports.ProductRepository = (*SQLiteRepository)(nil)
@@ -0,0 +1,453 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-crawler-go/internal/core/domain"
+	"web-crawler-go/internal/core/ports"
+)
+
+// Helpers shared by the database/sql repositories
+
+// sqlBatchSize is how many products are written per transaction
+const sqlBatchSize = 500
+
+// upsertProductsSQL runs query, which takes the arguments of upsertArgs, for every
+// product, one transaction per chunk. When a statement in a chunk fails the
+// chunk is rolled back and retried product by product, so the failing products are
+// reported in a *ports.ProductUpsertError while the others are still saved. Errors
+// that are not about one product, such as a cancelled context, stop the batch.
+func upsertProductsSQL(ctx context.Context, db *sql.DB, query string, products []*domain.Product) (int, error) {
+	var failures []ports.ProductUpsertFailure
+	saved := 0
+
+	for start := 0; start < len(products); start += sqlBatchSize {
+		end := min(start+sqlBatchSize, len(products))
+
+		args := make([][]any, end-start)
+		for i, product := range products[start:end] {
+			values, err := upsertArgs(product)
+			if err != nil {
+				failures = append(failures, ports.ProductUpsertFailure{Index: start + i, Name: product.Name, Err: err})
+				continue
+			}
+			args[i] = values
+		}
+
+		n, err := upsertChunkSQL(ctx, db, query, args)
+		if err == nil {
+			saved += n
+			continue
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return saved, ctxErr
+		}
+
+		// Find out which products the chunk failed on
+		for i, values := range args {
+			if values == nil {
+				continue
+			}
+			if _, err := db.ExecContext(ctx, query, values...); err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return saved, ctxErr
+				}
+				failures = append(failures, ports.ProductUpsertFailure{
+					Index: start + i,
+					Name:  products[start+i].Name,
+					Err:   fmt.Errorf("failed to upsert product: %w", err),
+				})
+				continue
+			}
+			saved++
+		}
+	}
+
+	if len(failures) > 0 {
+		return saved, &ports.ProductUpsertError{Failures: failures}
+	}
+	return saved, nil
+}
+
+// upsertChunkSQL runs query once per argument list in a single transaction,
+// skipping nil lists
+func upsertChunkSQL(ctx context.Context, db *sql.DB, query string, args [][]any) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	n := 0
+	for _, values := range args {
+		if values == nil {
+			continue
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return 0, err
+		}
+		n++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return n, nil
+}
+
+// upsertArgs returns the arguments of the upsert queries: domain, name, price,
+// price_discounted, description, images_url, tags, status, source_url, provider,
+// crawl_run_id, variants
+func upsertArgs(product *domain.Product) ([]any, error) {
+	imagesURL, tags, variants, err := encodeProductLists(product)
+	if err != nil {
+		return nil, err
+	}
+	return []any{product.Domain, product.Name, product.Price, product.PriceDiscounted,
+		product.Description, imagesURL, tags, product.Status, product.SourceURL, product.Provider, product.CrawlRunID,
+		variants}, nil
+}
+
+// sqlVariant is how a product variant is stored in the variants JSON column
+type sqlVariant struct {
+	Options         []string `json:"options"`
+	SKU             string   `json:"sku,omitempty"`
+	Price           int      `json:"price"`
+	PriceDiscounted int      `json:"price_discounted"`
+	Quantity        int      `json:"quantity"`
+}
+
+// encodeProductLists serializes the list fields for JSON columns, storing empty
+// lists rather than null
+func encodeProductLists(product *domain.Product) (string, string, string, error) {
+	imagesURL, err := json.Marshal(nonNil(product.ImagesURL))
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to encode image URLs: %w", err)
+	}
+	tags, err := json.Marshal(nonNil(product.Tags))
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to encode tags: %w", err)
+	}
+	stored := make([]sqlVariant, len(product.Variants))
+	for i, variant := range product.Variants {
+		stored[i] = sqlVariant{
+			Options:         nonNil(variant.Options),
+			SKU:             variant.SKU,
+			Price:           variant.Price,
+			PriceDiscounted: variant.PriceDiscounted,
+			Quantity:        variant.Quantity,
+		}
+	}
+	variants, err := json.Marshal(stored)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to encode variants: %w", err)
+	}
+	return string(imagesURL), string(tags), string(variants), nil
+}
+
+// productColumns are the columns scanProduct reads
+const productColumns = "id, domain, name, price, price_discounted, description, images_url, tags, variants, status, " +
+	"source_url, provider, crawl_run_id, created_at, updated_at, last_crawled_at"
+
+// sqlDialect holds what differs between the SQL repositories when querying products
+type sqlDialect struct {
+	// bind returns the placeholder of the nth argument, counting from 1
+	bind func(n int) string
+	// tagCondition matches products carrying the tag bound at placeholder, and
+	// tagArg converts the tag into that argument
+	tagCondition func(placeholder string) string
+	tagArg       func(tag string) any
+	// searchCondition matches the search term bound at placeholder, and searchArg
+	// converts the term into that argument
+	searchCondition func(placeholder string) string
+	searchArg       func(term string) any
+	// timeArg converts a time for comparison with updated_at
+	timeArg func(t time.Time) any
+	// tagElements is a FROM item listing the tags of each product as t.value
+	tagElements string
+	// imageCount is the number of image URLs of a product
+	imageCount string
+}
+
+// sqlArgs collects query arguments, handing out their placeholders
+type sqlArgs struct {
+	bind   func(n int) string
+	values []any
+}
+
+func (a *sqlArgs) add(value any) string {
+	a.values = append(a.values, value)
+	return a.bind(len(a.values))
+}
+
+// productWhere builds the WHERE clause of a product query, adding its arguments
+func (d sqlDialect) productWhere(query ports.ProductQuery, args *sqlArgs) string {
+	conditions := []string{"domain = " + args.add(query.Domain)}
+	if query.Status != "" {
+		conditions = append(conditions, "status = "+args.add(query.Status))
+	}
+	if query.Tag != "" {
+		conditions = append(conditions, d.tagCondition(args.add(d.tagArg(query.Tag))))
+	}
+	if query.MinPrice != nil {
+		conditions = append(conditions, "price >= "+args.add(*query.MinPrice))
+	}
+	if query.MaxPrice != nil {
+		conditions = append(conditions, "price <= "+args.add(*query.MaxPrice))
+	}
+	if query.OnSale {
+		conditions = append(conditions, "discount_percent > 0")
+	}
+	if !query.UpdatedSince.IsZero() {
+		conditions = append(conditions, "updated_at >= "+args.add(d.timeArg(query.UpdatedSince)))
+	}
+	if !query.CreatedSince.IsZero() {
+		conditions = append(conditions, "created_at >= "+args.add(d.timeArg(query.CreatedSince)))
+	}
+	if !query.CrawledSince.IsZero() {
+		conditions = append(conditions, "last_crawled_at >= "+args.add(d.timeArg(query.CrawledSince)))
+	}
+	if !query.CrawledBefore.IsZero() {
+		conditions = append(conditions, "last_crawled_at < "+args.add(d.timeArg(query.CrawledBefore)))
+	}
+	if query.CrawlRunID != "" {
+		conditions = append(conditions, "crawl_run_id = "+args.add(query.CrawlRunID))
+	}
+	if query.Provider != "" {
+		conditions = append(conditions, "provider = "+args.add(query.Provider))
+	}
+	if query.Search != "" {
+		conditions = append(conditions, d.searchCondition(args.add(d.searchArg(query.Search))))
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// sortColumn is the column behind a sort key, empty for insertion order
+func sortColumn(key ports.ProductSortKey) string {
+	switch key {
+	case ports.SortPrice:
+		return "price"
+	case ports.SortName:
+		return "name"
+	case ports.SortUpdatedAt:
+		return "updated_at"
+	case ports.SortDiscount:
+		return "discount_percent"
+	default:
+		return ""
+	}
+}
+
+// productOrder builds the ORDER BY clause of a product query, breaking ties by id
+// so pages do not overlap
+func productOrder(query ports.ProductQuery) string {
+	direction := "ASC"
+	if query.Descending {
+		direction = "DESC"
+	}
+	if column := sortColumn(query.Sort); column != "" {
+		return column + " " + direction + ", id " + direction
+	}
+	return "id " + direction
+}
+
+// keysetCondition selects the rows sorted after the cursor, adding its arguments
+func (d sqlDialect) keysetCondition(after *productCursor, args *sqlArgs) (string, error) {
+	id, err := strconv.ParseInt(after.ID, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("%w: bad ID: %v", ports.ErrInvalidCursor, err)
+	}
+	value, err := after.sortValue()
+	if err != nil {
+		return "", err
+	}
+	if t, ok := value.(time.Time); ok {
+		value = d.timeArg(t)
+	}
+
+	operator := " > "
+	if after.Descending {
+		operator = " < "
+	}
+	column := sortColumn(after.Sort)
+	if column == "" {
+		return "id" + operator + args.add(id), nil
+	}
+	return "(" + column + ", id)" + operator + "(" + args.add(value) + ", " + args.add(id) + ")", nil
+}
+
+// queryProducts selects a page of the products matching the query. Cursor pages
+// are selected with a keyset condition on the sort column and id instead of OFFSET.
+func (d sqlDialect) queryProducts(ctx context.Context, db *sql.DB, query ports.ProductQuery) (*ports.ProductPage, error) {
+	after, err := decodeProductCursor(query)
+	if err != nil {
+		return nil, err
+	}
+
+	args := &sqlArgs{bind: d.bind}
+	where := d.productWhere(query, args)
+	if after != nil {
+		condition, err := d.keysetCondition(after, args)
+		if err != nil {
+			return nil, err
+		}
+		where += " AND " + condition
+	}
+
+	sortValue := sortColumn(query.Sort)
+	if sortValue == "" {
+		sortValue = "id"
+	}
+	// One extra row tells whether there is a next page
+	statement := "SELECT " + sortValue + ", " + productColumns + " FROM products WHERE " + where +
+		" ORDER BY " + productOrder(query) +
+		" LIMIT " + args.add(query.PageSize+1) + " OFFSET " + args.add(query.Offset())
+
+	rows, err := db.QueryContext(ctx, statement, args.values...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products: %w", err)
+	}
+	defer rows.Close()
+
+	page := &ports.ProductPage{Products: make([]*domain.Product, 0)}
+	var lastValue any
+	for rows.Next() {
+		var value any
+		product, err := scanProduct(rows, &value)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(page.Products) == query.PageSize {
+			// The extra row: the page ends with the previous one
+			if b, ok := lastValue.([]byte); ok {
+				lastValue = string(b)
+			}
+			last := page.Products[len(page.Products)-1]
+			page.NextCursor, err = encodeProductCursor(query, lastValue, last.ID)
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+		page.Products = append(page.Products, product)
+		lastValue = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate products: %w", err)
+	}
+	return page, nil
+}
+
+// countProducts counts the products matching the query
+func (d sqlDialect) countProducts(ctx context.Context, db *sql.DB, query ports.ProductQuery) (int, error) {
+	args := &sqlArgs{bind: d.bind}
+	statement := "SELECT COUNT(*) FROM products WHERE " + d.productWhere(query, args)
+
+	var total int
+	if err := db.QueryRowContext(ctx, statement, args.values...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count products: %w", err)
+	}
+	return total, nil
+}
+
+// scanProduct reads a row selected as the extra columns followed by productColumns
+func scanProduct(rows interface{ Scan(...any) error }, extra ...any) (*domain.Product, error) {
+	var product domain.Product
+	var id int64
+	var imagesURL, tags, variants []byte
+	var createdAt, updatedAt, lastCrawledAt sqlTime
+	dest := append(extra, &id, &product.Domain, &product.Name, &product.Price, &product.PriceDiscounted,
+		&product.Description, &imagesURL, &tags, &variants, &product.Status,
+		&product.SourceURL, &product.Provider, &product.CrawlRunID, &createdAt, &updatedAt, &lastCrawledAt)
+	if err := rows.Scan(dest...); err != nil {
+		return nil, fmt.Errorf("failed to scan product: %w", err)
+	}
+	if err := json.Unmarshal(imagesURL, &product.ImagesURL); err != nil {
+		return nil, fmt.Errorf("failed to decode image URLs: %w", err)
+	}
+	if err := json.Unmarshal(tags, &product.Tags); err != nil {
+		return nil, fmt.Errorf("failed to decode tags: %w", err)
+	}
+	var stored []sqlVariant
+	if err := json.Unmarshal(variants, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode variants: %w", err)
+	}
+	for _, variant := range stored {
+		product.Variants = append(product.Variants, domain.ProductVariant{
+			Options:         variant.Options,
+			SKU:             variant.SKU,
+			Price:           variant.Price,
+			PriceDiscounted: variant.PriceDiscounted,
+			Quantity:        variant.Quantity,
+		})
+	}
+	product.ID = strconv.FormatInt(id, 10)
+	product.CreatedAt, product.UpdatedAt = time.Time(createdAt), time.Time(updatedAt)
+	product.LastCrawledAt = time.Time(lastCrawledAt)
+	return &product, nil
+}
+
+// getProduct reads the product with the ID, a row id
+func (d sqlDialect) getProduct(ctx context.Context, db *sql.DB, id string) (*domain.Product, error) {
+	rowID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, ports.ErrProductNotFound
+	}
+
+	row := db.QueryRowContext(ctx, "SELECT "+productColumns+" FROM products WHERE id = "+d.bind(1), rowID)
+	product, err := scanProduct(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ports.ErrProductNotFound
+	}
+	return product, err
+}
+
+// sqlTime scans timestamps, which PostgreSQL returns as times and SQLite as
+// RFC 3339 text. NULL scans as the zero time.
+type sqlTime time.Time
+
+func (t *sqlTime) Scan(src any) error {
+	switch value := src.(type) {
+	case nil:
+		*t = sqlTime{}
+	case time.Time:
+		*t = sqlTime(value)
+	case string:
+		return t.parse(value)
+	case []byte:
+		return t.parse(string(value))
+	default:
+		return fmt.Errorf("cannot scan %T into a time", src)
+	}
+	return nil
+}
+
+func (t *sqlTime) parse(value string) error {
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return err
+	}
+	*t = sqlTime(parsed)
+	return nil
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,131 @@
+package warc
+
+import (
+	"bufio"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// cdxHeader names the CDX 11 columns: SURT key, timestamp, original URL, MIME type,
+// status, payload digest, redirect, meta tags, record length, offset and file name
+const cdxHeader = " CDX N b a m s k r M S V g"
+
+// cdxTimestamp is the 14 digit timestamp layout used in CDX lines
+const cdxTimestamp = "20060102150405"
+
+// cdxEntry locates a response record in a WARC file
+type cdxEntry struct {
+	url         string
+	fetchedAt   time.Time
+	contentType string
+	statusCode  int
+	digest      string
+	redirect    string
+	length      int64
+	offset      int64
+	file        string
+}
+
+func (e cdxEntry) String() string {
+	mediaType, _, err := mime.ParseMediaType(e.contentType)
+	if err != nil || mediaType == "" {
+		mediaType = "-"
+	}
+	return strings.Join([]string{
+		surtKey(e.url),
+		e.fetchedAt.UTC().Format(cdxTimestamp),
+		e.url,
+		mediaType,
+		fmt.Sprint(e.statusCode),
+		strings.TrimPrefix(e.digest, "sha1:"),
+		orDash(e.redirect),
+		"-",
+		fmt.Sprint(e.length),
+		fmt.Sprint(e.offset),
+		e.file,
+	}, " ")
+}
+
+// surtKey canonicalizes a URL into the Sort-friendly URI Reordering Transform used
+// as the CDX key, e.g. "https://www.shop.com.tw/p?a=1" becomes "tw,com,shop)/p?a=1"
+func surtKey(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return strings.ToLower(rawURL)
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	labels := strings.Split(host, ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+
+	key := strings.Join(labels, ",")
+	if port := parsed.Port(); port != "" && port != "80" && port != "443" {
+		key += ":" + port
+	}
+
+	path := parsed.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	key += ")" + strings.ToLower(path)
+	if parsed.RawQuery != "" {
+		key += "?" + strings.ToLower(parsed.RawQuery)
+	}
+	return key
+}
+
+// sortCDX rewrites an index written in fetch order as a sorted CDX file with its header
+func sortCDX(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" && line != cdxHeader {
+			lines = append(lines, line)
+		}
+	}
+	file.Close()
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read CDX index: %w", err)
+	}
+	sort.Strings(lines)
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cdx-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	writer.WriteString(cdxHeader + "\n")
+	for _, line := range lines {
+		writer.WriteString(line + "\n")
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,134 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Response is an archived HTTP response
+type Response struct {
+	URL        string
+	RunID      string
+	Date       time.Time
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// ReadResponses calls fn for every response record in a .warc or .warc.gz file,
+// in file order. Other record types are skipped.
+func ReadResponses(path string, fn func(*Response) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open WARC file: %w", err)
+	}
+	defer file.Close()
+
+	var source io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		// Every record is its own gzip member; the reader walks through all of them
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		defer gz.Close()
+		source = gz
+	}
+
+	reader := bufio.NewReader(source)
+	for {
+		r, err := readRecord(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if r.get("WARC-Type") != "response" {
+			continue
+		}
+
+		response, err := parseResponse(r)
+		if err != nil {
+			return fmt.Errorf("failed to parse response for %s in %s: %w", r.get("WARC-Target-URI"), path, err)
+		}
+		if err := fn(response); err != nil {
+			return err
+		}
+	}
+}
+
+// readRecord reads the next record, returning io.EOF when the input is exhausted
+func readRecord(reader *bufio.Reader) (*record, error) {
+	// Skip the blank lines separating records
+	var version string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) && strings.TrimSpace(line) == "" {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+		if version = strings.TrimSpace(line); version != "" {
+			break
+		}
+	}
+	if !strings.HasPrefix(version, "WARC/1.") {
+		return nil, fmt.Errorf("unexpected record start %q", version)
+	}
+
+	headers, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read record headers: %w", err)
+	}
+	length, err := strconv.ParseInt(headers.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid record Content-Length: %w", err)
+	}
+
+	r := &record{block: make([]byte, length)}
+	for name, values := range headers {
+		for _, value := range values {
+			r.add(name, value)
+		}
+	}
+	if _, err := io.ReadFull(reader, r.block); err != nil {
+		return nil, fmt.Errorf("truncated record block: %w", err)
+	}
+	return r, nil
+}
+
+// parseResponse decodes the HTTP message held by a response record
+func parseResponse(r *record) (*Response, error) {
+	httpResponse, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(r.block)), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+
+	body, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	date, _ := time.Parse(time.RFC3339Nano, r.get("WARC-Date"))
+	return &Response{
+		URL:        strings.Trim(r.get("WARC-Target-URI"), "<>"),
+		RunID:      r.get(RunIDHeader),
+		Date:       date,
+		StatusCode: httpResponse.StatusCode,
+		Header:     httpResponse.Header,
+		Body:       body,
+	}, nil
+}
@@ -0,0 +1,133 @@
+package warc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"web-crawler-go/internal/core/ports"
+)
+
+const (
+	warcVersion = "WARC/1.1"
+	// dateFormat is the WARC-Date layout; WARC 1.1 allows sub-second precision
+	dateFormat = "2006-01-02T15:04:05.000000Z"
+	// RunIDHeader is the extension field tagging records with the crawl run
+	RunIDHeader = "WARC-Crawl-Run-ID"
+)
+
+// field is one named header of a WARC record; order is preserved on output
+type field struct {
+	name  string
+	value string
+}
+
+// record is a WARC record ready to be serialized
+type record struct {
+	fields []field
+	block  []byte
+}
+
+func (r *record) add(name, value string) {
+	r.fields = append(r.fields, field{name: name, value: value})
+}
+
+// get returns the first value of a header, or ""
+func (r *record) get(name string) string {
+	for _, f := range r.fields {
+		if http.CanonicalHeaderKey(f.name) == http.CanonicalHeaderKey(name) {
+			return f.value
+		}
+	}
+	return ""
+}
+
+// bytes serializes the record: version line, headers, blank line, block and the
+// two CRLF separators that end every record
+func (r *record) bytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(warcVersion + "\r\n")
+	for _, f := range r.fields {
+		buf.WriteString(f.name + ": " + f.value + "\r\n")
+	}
+	buf.WriteString("Content-Length: " + strconv.Itoa(len(r.block)) + "\r\n")
+	buf.WriteString("\r\n")
+	buf.Write(r.block)
+	buf.WriteString("\r\n\r\n")
+	return buf.Bytes()
+}
+
+// newRecordID returns a random urn:uuid record identifier
+func newRecordID() string {
+	var u [16]byte
+	_, _ = rand.Read(u[:])
+	u[6] = (u[6] & 0x0f) | 0x40 // version 4
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// digest returns the labelled base32 SHA-1 digest used by WARC and CDX
+func digest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// requestBlock renders the request as an HTTP/1.1 message
+func requestBlock(exchange ports.RecordedExchange) ([]byte, error) {
+	target, err := url.Parse(exchange.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", exchange.URL, err)
+	}
+	method := exchange.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s HTTP/1.1\r\n", method, target.RequestURI())
+	fmt.Fprintf(&buf, "Host: %s\r\n", target.Host)
+	if err := exchange.RequestHeader.Write(&buf); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\r\n")
+	return buf.Bytes(), nil
+}
+
+// responseBlock renders the response as an HTTP message. The body was already
+// de-chunked and decompressed by the client, so the framing headers are rewritten
+// to describe the stored payload.
+func responseBlock(exchange ports.RecordedExchange) []byte {
+	header := exchange.ResponseHeader.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Del("Transfer-Encoding")
+	header.Set("Content-Length", strconv.Itoa(len(exchange.Body)))
+
+	proto := exchange.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	status := exchange.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", exchange.StatusCode, http.StatusText(exchange.StatusCode))
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s\r\n", proto, status)
+	_ = header.Write(&buf)
+	buf.WriteString("\r\n")
+	buf.Write(exchange.Body)
+	return buf.Bytes()
+}
+
+// formatDate renders a WARC-Date
+func formatDate(t time.Time) string {
+	return t.UTC().Format(dateFormat)
+}
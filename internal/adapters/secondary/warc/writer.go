@@ -0,0 +1,237 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"web-crawler-go/internal/core/ports"
+)
+
+const (
+	defaultPrefix      = "crawl"
+	defaultMaxFileSize = 1 << 30 // 1 GiB
+	fileExt            = ".warc.gz"
+	cdxExt             = ".cdx"
+	software           = "web-crawler-go"
+)
+
+// WriterConfig configures where and how WARC files are written
+type WriterConfig struct {
+	Dir string
+	// Prefix starts every file name; defaults to "crawl"
+	Prefix string
+	// MaxFileSize is the compressed size after which a new file is started;
+	// defaults to 1 GiB. A request and its response always share a file.
+	MaxFileSize int64
+}
+
+// Writer implements ports.FetchRecorder by appending request and response records
+// to rotating gzip-compressed WARC 1.1 files. Each record is its own gzip member so
+// it can be read at its offset, and every file gets a CDX index next to it listing
+// its responses. The index is written in fetch order and sorted when the file is
+// closed.
+type Writer struct {
+	mu     sync.Mutex
+	config WriterConfig
+	logger ports.Logger
+
+	file       *os.File
+	cdx        *os.File
+	fileName   string
+	size       int64
+	serial     int
+	warcinfoID string
+	closed     bool
+}
+
+// ErrWriterClosed is returned when an exchange is recorded after Close
+var ErrWriterClosed = errors.New("WARC writer is closed")
+
+// NewWriter creates a WARC writer in config.Dir. Files are created lazily on the
+// first recorded exchange.
+func NewWriter(config WriterConfig, logger ports.Logger) (*Writer, error) {
+	if config.Dir == "" {
+		return nil, errors.New("WARC directory is required")
+	}
+	if config.Prefix == "" {
+		config.Prefix = defaultPrefix
+	}
+	if config.MaxFileSize <= 0 {
+		config.MaxFileSize = defaultMaxFileSize
+	}
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create WARC directory: %w", err)
+	}
+
+	return &Writer{config: config, logger: logger}, nil
+}
+
+// Record implements ports.FetchRecorder, writing a response record and the request
+// record concurrent to it
+func (w *Writer) Record(ctx context.Context, exchange ports.RecordedExchange) error {
+	request, err := requestBlock(exchange)
+	if err != nil {
+		return err
+	}
+	response := responseBlock(exchange)
+
+	fetchedAt := exchange.FetchedAt
+	if fetchedAt.IsZero() {
+		fetchedAt = time.Now()
+	}
+	payloadDigest := digest(exchange.Body)
+	responseID := newRecordID()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrWriterClosed
+	}
+	if w.file == nil || w.size >= w.config.MaxFileSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	responseRecord := &record{block: response}
+	responseRecord.add("WARC-Type", "response")
+	responseRecord.add("WARC-Record-ID", responseID)
+	responseRecord.add("WARC-Date", formatDate(fetchedAt))
+	responseRecord.add("WARC-Target-URI", exchange.URL)
+	responseRecord.add("WARC-Warcinfo-ID", w.warcinfoID)
+	if exchange.RunID != "" {
+		responseRecord.add(RunIDHeader, exchange.RunID)
+	}
+	responseRecord.add("WARC-Payload-Digest", payloadDigest)
+	responseRecord.add("WARC-Block-Digest", digest(response))
+	responseRecord.add("Content-Type", "application/http;msgtype=response")
+
+	offset, length, err := w.write(responseRecord)
+	if err != nil {
+		return err
+	}
+
+	requestRecord := &record{block: request}
+	requestRecord.add("WARC-Type", "request")
+	requestRecord.add("WARC-Record-ID", newRecordID())
+	requestRecord.add("WARC-Date", formatDate(fetchedAt))
+	requestRecord.add("WARC-Target-URI", exchange.URL)
+	requestRecord.add("WARC-Warcinfo-ID", w.warcinfoID)
+	requestRecord.add("WARC-Concurrent-To", responseID)
+	if exchange.RunID != "" {
+		requestRecord.add(RunIDHeader, exchange.RunID)
+	}
+	requestRecord.add("WARC-Block-Digest", digest(request))
+	requestRecord.add("Content-Type", "application/http;msgtype=request")
+
+	if _, _, err := w.write(requestRecord); err != nil {
+		return err
+	}
+
+	entry := cdxEntry{
+		url:         exchange.URL,
+		fetchedAt:   fetchedAt,
+		contentType: exchange.ResponseHeader.Get("Content-Type"),
+		statusCode:  exchange.StatusCode,
+		digest:      payloadDigest,
+		redirect:    exchange.ResponseHeader.Get("Location"),
+		length:      length,
+		offset:      offset,
+		file:        w.fileName,
+	}
+	if _, err := w.cdx.WriteString(entry.String() + "\n"); err != nil {
+		return fmt.Errorf("failed to write CDX entry: %w", err)
+	}
+	return nil
+}
+
+// Close finishes the current file and sorts its index. Exchanges recorded
+// afterwards, by fetches still running during shutdown, are rejected rather than
+// starting a file nothing would close.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	return w.closeFile()
+}
+
+// rotate closes the current file and starts the next one with a warcinfo record
+func (w *Writer) rotate() error {
+	if err := w.closeFile(); err != nil {
+		w.logger.Error("failed to close WARC file", "file", w.fileName, "error", err)
+	}
+
+	w.serial++
+	name := fmt.Sprintf("%s-%s-%05d%s", w.config.Prefix, time.Now().UTC().Format(cdxTimestamp), w.serial, fileExt)
+	path := filepath.Join(w.config.Dir, name)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create WARC file: %w", err)
+	}
+	cdx, err := os.OpenFile(strings.TrimSuffix(path, fileExt)+cdxExt, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to create CDX index: %w", err)
+	}
+	w.file, w.cdx, w.fileName, w.size = file, cdx, name, 0
+
+	info := &record{block: []byte("software: " + software + "\r\nformat: WARC File Format 1.1\r\nconformsTo: http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/\r\n")}
+	w.warcinfoID = newRecordID()
+	info.add("WARC-Type", "warcinfo")
+	info.add("WARC-Record-ID", w.warcinfoID)
+	info.add("WARC-Date", formatDate(time.Now()))
+	info.add("WARC-Filename", name)
+	info.add("Content-Type", "application/warc-fields")
+	if _, _, err := w.write(info); err != nil {
+		return err
+	}
+
+	w.logger.Info("started WARC file", "file", name)
+	return nil
+}
+
+// write appends a record as its own gzip member and returns where it starts and
+// its compressed length
+func (w *Writer) write(r *record) (int64, int64, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(r.bytes()); err != nil {
+		return 0, 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return 0, 0, err
+	}
+
+	offset := w.size
+	n, err := w.file.Write(buf.Bytes())
+	w.size += int64(n)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to write WARC record: %w", err)
+	}
+	return offset, int64(n), nil
+}
+
+// closeFile closes the current WARC file and sorts its CDX index
+func (w *Writer) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+
+	errs := []error{w.file.Close(), w.cdx.Close()}
+	errs = append(errs, sortCDX(w.cdx.Name()))
+	w.file, w.cdx = nil, nil
+	return errors.Join(errs...)
+}
+
+// Ensure Writer implements FetchRecorder
+var _ ports.FetchRecorder = (*Writer)(nil)
@@ -0,0 +1,255 @@
+package warc
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"web-crawler-go/internal/core/ports"
+	"web-crawler-go/internal/core/services/loggerservice"
+)
+
+var fetchedAt = time.Date(2026, 3, 14, 9, 26, 53, 589793000, time.UTC)
+
+// exchanges are recorded in an order that is not the CDX sort order
+var exchanges = []ports.RecordedExchange{
+	{
+		RunID:          "run-1",
+		URL:            "https://www.shop.example.tw/products/oolong?ref=home",
+		RequestHeader:  http.Header{"User-Agent": {"web-crawler-go"}},
+		StatusCode:     http.StatusOK,
+		ResponseHeader: http.Header{"Content-Type": {"text/html; charset=utf-8"}, "Transfer-Encoding": {"chunked"}},
+		Body:           []byte("<html>凍頂烏龍茶 450 TWD</html>"),
+		FetchedAt:      fetchedAt,
+	},
+	{
+		RunID:          "run-1",
+		URL:            "https://books.example.jp/item/42",
+		StatusCode:     http.StatusMovedPermanently,
+		ResponseHeader: http.Header{"Location": {"https://books.example.jp/item/42/"}},
+		FetchedAt:      fetchedAt.Add(time.Second),
+	},
+	{
+		URL:            "https://shop.example.tw/api/products.json",
+		Method:         http.MethodPost,
+		StatusCode:     http.StatusOK,
+		ResponseHeader: http.Header{"Content-Type": {"application/json"}},
+		Body:           []byte(`{"products":[]}`),
+		FetchedAt:      fetchedAt.Add(2 * time.Second),
+	},
+}
+
+func newTestWriter(t *testing.T, maxFileSize int64) (*Writer, string) {
+	t.Helper()
+	dir := t.TempDir()
+	writer, err := NewWriter(WriterConfig{Dir: dir, MaxFileSize: maxFileSize}, loggerservice.NewLoggerService())
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	return writer, dir
+}
+
+func recordAndClose(t *testing.T, writer *Writer, exchanges ...ports.RecordedExchange) {
+	t.Helper()
+	for _, exchange := range exchanges {
+		if err := writer.Record(context.Background(), exchange); err != nil {
+			t.Fatalf("Record %s: %v", exchange.URL, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func warcFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*"+fileExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(files)
+	return files
+}
+
+func readAll(t *testing.T, path string) []*Response {
+	t.Helper()
+	var responses []*Response
+	err := ReadResponses(path, func(response *Response) error {
+		responses = append(responses, response)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadResponses: %v", err)
+	}
+	return responses
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	writer, dir := newTestWriter(t, 0)
+	recordAndClose(t, writer, exchanges...)
+
+	files := warcFiles(t, dir)
+	if len(files) != 1 {
+		t.Fatalf("wrote %d WARC files, want 1", len(files))
+	}
+	responses := readAll(t, files[0])
+	if len(responses) != len(exchanges) {
+		t.Fatalf("read %d responses, want %d", len(responses), len(exchanges))
+	}
+
+	for i, response := range responses {
+		want := exchanges[i]
+		if response.URL != want.URL || response.RunID != want.RunID || response.StatusCode != want.StatusCode {
+			t.Errorf("response %d = %s run %q status %d, want %s run %q status %d",
+				i, response.URL, response.RunID, response.StatusCode, want.URL, want.RunID, want.StatusCode)
+		}
+		if !response.Date.Equal(want.FetchedAt) {
+			t.Errorf("response %d date = %v, want %v", i, response.Date, want.FetchedAt)
+		}
+		if string(response.Body) != string(want.Body) {
+			t.Errorf("response %d body = %q, want %q", i, response.Body, want.Body)
+		}
+		// The body is stored decoded, so the framing describes it as is
+		if response.Header.Get("Transfer-Encoding") != "" || response.Header.Get("Content-Length") != strconv.Itoa(len(want.Body)) {
+			t.Errorf("response %d framing = %v, want Content-Length %d", i, response.Header, len(want.Body))
+		}
+	}
+	if got := responses[1].Header.Get("Location"); got != "https://books.example.jp/item/42/" {
+		t.Errorf("redirect Location = %q", got)
+	}
+}
+
+func TestWriterIndexesResponsesInSortedCDX(t *testing.T) {
+	writer, dir := newTestWriter(t, 0)
+	recordAndClose(t, writer, exchanges...)
+
+	warcFile := warcFiles(t, dir)[0]
+	index, err := os.ReadFile(strings.TrimSuffix(warcFile, fileExt) + cdxExt)
+	if err != nil {
+		t.Fatalf("CDX index: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(index), "\n"), "\n")
+	if lines[0] != cdxHeader {
+		t.Errorf("first line = %q, want the CDX header", lines[0])
+	}
+	entries := lines[1:]
+	if len(entries) != len(exchanges) {
+		t.Fatalf("indexed %d responses, want %d", len(entries), len(exchanges))
+	}
+	if !slices.IsSorted(entries) {
+		t.Errorf("index is not sorted:\n%s", index)
+	}
+
+	wantKeys := []string{"jp,example,books)/item/42", "tw,example,shop)/api/products.json", "tw,example,shop)/products/oolong?ref=home"}
+	for i, entry := range entries {
+		columns := strings.Fields(entry)
+		if len(columns) != 11 {
+			t.Fatalf("entry %q has %d columns, want 11", entry, len(columns))
+		}
+		if columns[0] != wantKeys[i] {
+			t.Errorf("entry %d key = %q, want %q", i, columns[0], wantKeys[i])
+		}
+		if columns[10] != filepath.Base(warcFile) {
+			t.Errorf("entry %d file = %q, want %q", i, columns[10], filepath.Base(warcFile))
+		}
+
+		// The length and offset locate a gzip member holding just that response
+		length, _ := strconv.ParseInt(columns[8], 10, 64)
+		offset, _ := strconv.ParseInt(columns[9], 10, 64)
+		r := readRecordAt(t, warcFile, offset, length)
+		if r.get("WARC-Type") != "response" || r.get("WARC-Target-URI") != columns[2] {
+			t.Errorf("entry %d points at a %s record for %s, want the response for %s",
+				i, r.get("WARC-Type"), r.get("WARC-Target-URI"), columns[2])
+		}
+		if "sha1:"+columns[5] != r.get("WARC-Payload-Digest") {
+			t.Errorf("entry %d digest = %s, record has %s", i, columns[5], r.get("WARC-Payload-Digest"))
+		}
+	}
+
+	redirect := strings.Fields(entries[0])
+	if redirect[3] != "-" || redirect[4] != "301" || redirect[6] != "https://books.example.jp/item/42/" {
+		t.Errorf("redirect entry = %q", entries[0])
+	}
+	if page := strings.Fields(entries[2]); page[1] != "20260314092653" || page[3] != "text/html" {
+		t.Errorf("page entry = %q", entries[2])
+	}
+}
+
+func readRecordAt(t *testing.T, path string, offset, length int64) *record {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(io.NewSectionReader(file, offset, length))
+	if err != nil {
+		t.Fatalf("gzip member at %d: %v", offset, err)
+	}
+	defer gz.Close()
+	r, err := readRecord(bufio.NewReader(gz))
+	if err != nil {
+		t.Fatalf("record at %d: %v", offset, err)
+	}
+	return r
+}
+
+func TestWriterRotatesFiles(t *testing.T) {
+	// Every exchange overflows the limit, so each starts a file of its own
+	writer, dir := newTestWriter(t, 1)
+	recordAndClose(t, writer, exchanges[0], exchanges[2])
+
+	files := warcFiles(t, dir)
+	if len(files) != 2 {
+		t.Fatalf("wrote %d WARC files, want 2", len(files))
+	}
+	for i, file := range files {
+		responses := readAll(t, file)
+		if len(responses) != 1 || responses[0].URL != []string{exchanges[0].URL, exchanges[2].URL}[i] {
+			t.Errorf("%s holds %d responses, want only exchange %d", filepath.Base(file), len(responses), i)
+		}
+		if _, err := os.Stat(strings.TrimSuffix(file, fileExt) + cdxExt); err != nil {
+			t.Errorf("%s has no index: %v", filepath.Base(file), err)
+		}
+	}
+}
+
+func TestWriterRejectsRecordsAfterClose(t *testing.T) {
+	writer, dir := newTestWriter(t, 0)
+	recordAndClose(t, writer, exchanges[0])
+
+	if err := writer.Record(context.Background(), exchanges[1]); !errors.Is(err, ErrWriterClosed) {
+		t.Errorf("Record after Close = %v, want ErrWriterClosed", err)
+	}
+	if files := warcFiles(t, dir); len(files) != 1 {
+		t.Errorf("a late record started another file: %v", files)
+	}
+}
+
+func TestSURTKey(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://www.shop.com.tw/p?a=1", want: "tw,com,shop)/p?a=1"},
+		{url: "http://Shop.Example.TW", want: "tw,example,shop)/"},
+		{url: "https://shop.example.tw:443/Cart", want: "tw,example,shop)/cart"},
+		{url: "http://localhost:8080/api", want: "localhost:8080)/api"},
+		{url: "not a url", want: "not a url"},
+	}
+	for _, tt := range tests {
+		if got := surtKey(tt.url); got != tt.want {
+			t.Errorf("surtKey(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
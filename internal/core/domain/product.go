@@ -1,12 +1,69 @@
 package domain
 
+import "time"
+
+// Product statuses set by the providers
+const (
+	StatusActive     = "active"
+	StatusOutOfStock = "outOfStock"
+)
+
 // Product represents the core business entity.
 type Product struct {
+	// ID is assigned by the repository when the product is first saved and stays
+	// the same when the product is crawled again
+	ID string
+	// Domain is the host the product was crawled from, e.g. "shop.example.tw"
+	Domain          string
 	Name            string
 	Price           int
 	PriceDiscounted int
 	Description     string
 	ImagesURL       []string
 	Tags            []string
-	Status          string
+	// Variants are the purchasable options of the product; empty when it is only
+	// sold one way
+	Variants []ProductVariant
+	Status   string
+	// SourceURL is the page the product was parsed from
+	SourceURL string
+	// Provider is the registry name of the provider that parsed the product
+	Provider string
+	// CrawlRunID is the crawl that last saved the product
+	CrawlRunID string
+	// CreatedAt (first seen), UpdatedAt (last saved) and LastCrawledAt (last
+	// saved by a crawl) are kept by the repository. Saving a product without a
+	// CrawlRunID leaves its crawl run and LastCrawledAt as they were.
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	LastCrawledAt time.Time
+}
+
+// ProductVariant is one purchasable option of a product, such as a size or colour
+type ProductVariant struct {
+	// Options are the values of the variant's fields, e.g. ["Large", "Green"]
+	Options         []string
+	SKU             string
+	Price           int
+	PriceDiscounted int
+	Quantity        int
+}
+
+// InStock reports whether the product can be bought
+func (p *Product) InStock() bool {
+	return p.Status != StatusOutOfStock
+}
+
+// OnSale reports whether the product has a discounted price below its list price
+func (p *Product) OnSale() bool {
+	return p.PriceDiscounted > 0 && p.PriceDiscounted < p.Price
+}
+
+// DiscountPercent is how much of the list price the discount takes off, from 0 to
+// 100; it is 0 when the product is not on sale
+func (p *Product) DiscountPercent() float64 {
+	if !p.OnSale() {
+		return 0
+	}
+	return float64(p.Price-p.PriceDiscounted) * 100 / float64(p.Price)
 }
@@ -2,7 +2,9 @@ package ports
 
 import (
 	"context"
+	"errors"
 	"io"
+	"net/http"
 	"time"
 )
 
@@ -17,3 +19,132 @@ type CacheService interface {
 	// Delete removes data from the cache for the given key
 	Delete(ctx context.Context, key string) error
 }
+
+// CacheStats reports usage counters for a cache backend
+type CacheStats struct {
+	Backend     string `json:"backend"`
+	Hits        int64  `json:"hits"`
+	Misses      int64  `json:"misses"`
+	Entries     int64  `json:"entries"`
+	SizeBytes   int64  `json:"size_bytes"`
+	Evictions   int64  `json:"evictions"`
+	Expirations int64  `json:"expirations"`
+
+	// Set by layered caches: Tier names the layer, Promotions counts values copied
+	// into it from a lower layer and Errors counts failed operations on it
+	Tier       string       `json:"tier,omitempty"`
+	Promotions int64        `json:"promotions,omitempty"`
+	Errors     int64        `json:"errors,omitempty"`
+	Tiers      []CacheStats `json:"tiers,omitempty"`
+
+	// Set by compressing caches for the values they have written
+	Codec             string  `json:"codec,omitempty"`
+	UncompressedBytes int64   `json:"uncompressed_bytes,omitempty"`
+	StoredBytes       int64   `json:"stored_bytes,omitempty"`
+	CompressionRatio  float64 `json:"compression_ratio,omitempty"`
+	BytesSaved        int64   `json:"bytes_saved,omitempty"`
+}
+
+// CacheStatsProvider is implemented by caches that keep usage statistics
+type CacheStatsProvider interface {
+	Stats(ctx context.Context) (CacheStats, error)
+}
+
+// CacheEntryMetadata describes the HTTP response a cached body came from
+type CacheEntryMetadata struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	FetchedAt  time.Time   `json:"fetched_at"`
+}
+
+// CacheEntryMetadataProvider is implemented by cached bodies returned with the
+// metadata that was stored alongside them
+type CacheEntryMetadataProvider interface {
+	CacheEntryMetadata() CacheEntryMetadata
+}
+
+// CacheEntryStorage describes how a cached entry is stored
+type CacheEntryStorage struct {
+	// ExpiresAt is when the entry expires; zero when it never does
+	ExpiresAt time.Time
+	// Tags are the tags the entry was stored with
+	Tags []string
+}
+
+// CacheEntryStorageProvider is implemented by cached bodies returned with the
+// expiry and tags of their entry, so layered caches can copy the entry faithfully
+type CacheEntryStorageProvider interface {
+	CacheEntryStorage() CacheEntryStorage
+}
+
+type cacheEntryMetadataKey struct{}
+
+// WithCacheEntryMetadata attaches response metadata to the context passed to
+// CacheService.Set, for backends that keep it next to the body. Backends that
+// only store bytes ignore it.
+func WithCacheEntryMetadata(ctx context.Context, metadata CacheEntryMetadata) context.Context {
+	return context.WithValue(ctx, cacheEntryMetadataKey{}, metadata)
+}
+
+// CacheEntryMetadataFromContext returns the metadata attached with WithCacheEntryMetadata
+func CacheEntryMetadataFromContext(ctx context.Context) (CacheEntryMetadata, bool) {
+	metadata, ok := ctx.Value(cacheEntryMetadataKey{}).(CacheEntryMetadata)
+	return metadata, ok
+}
+
+// TaggedCache is implemented by caches that index keys by the tags they were stored
+// with, so related entries can be removed together
+type TaggedCache interface {
+	// KeysByTag lists the keys currently stored with tag
+	KeysByTag(ctx context.Context, tag string) ([]string, error)
+
+	// DeleteByTag removes every entry stored with tag and returns how many were removed
+	DeleteByTag(ctx context.Context, tag string) (int, error)
+}
+
+type cacheTagsKey struct{}
+
+// WithCacheTags attaches tags to the context passed to CacheService.Set. Caches
+// implementing TaggedCache index the key under each tag; others ignore them.
+func WithCacheTags(ctx context.Context, tags ...string) context.Context {
+	return context.WithValue(ctx, cacheTagsKey{}, tags)
+}
+
+// CacheTagsFromContext returns the tags attached with WithCacheTags
+func CacheTagsFromContext(ctx context.Context) []string {
+	tags, _ := ctx.Value(cacheTagsKey{}).([]string)
+	return tags
+}
+
+// ErrCacheUnsupported is returned by CacheAdmin operations the configured cache cannot perform
+var ErrCacheUnsupported = errors.New("operation not supported by the configured cache")
+
+// CachedURL describes the cache entry held for a URL
+type CachedURL struct {
+	URL        string `json:"url"`
+	Key        string `json:"key"`
+	SizeBytes  int64  `json:"size_bytes"`
+	StatusCode int    `json:"status_code,omitempty"`
+	// NotFound is set when the entry is a remembered 404 or 410 rather than a body
+	NotFound    bool        `json:"not_found,omitempty"`
+	ContentType string      `json:"content_type,omitempty"`
+	FetchedAt   time.Time   `json:"fetched_at,omitzero"`
+	Header      http.Header `json:"header,omitempty"`
+}
+
+// CacheAdmin inspects and invalidates cached fetches by URL and domain
+type CacheAdmin interface {
+	// LookupURL returns the cache entry for url, if any
+	LookupURL(ctx context.Context, url string) (*CachedURL, bool, error)
+
+	// InvalidateURL removes the cache entries for url
+	InvalidateURL(ctx context.Context, url string) error
+
+	// InvalidateDomain removes every entry fetched from domain or its subdomains
+	// and returns how many were removed
+	InvalidateDomain(ctx context.Context, domain string) (int, error)
+
+	// CacheStats reports the statistics of the underlying cache
+	CacheStats(ctx context.Context) (CacheStats, error)
+}
@@ -0,0 +1,143 @@
+package ports
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Crawl statuses
+const (
+	CrawlCompleted = "completed"
+	CrawlFailed    = "failed"
+)
+
+// CrawlRecord is the outcome of one crawl of a domain
+type CrawlRecord struct {
+	RunID    string `json:"run_id"`
+	Domain   string `json:"domain"`
+	Provider string `json:"provider,omitempty"`
+	// Status is CrawlCompleted or CrawlFailed
+	Status        string    `json:"status"`
+	ProductsCount int       `json:"products_count"`
+	Error         string    `json:"error,omitempty"`
+	StartedAt     time.Time `json:"started_at"`
+	FinishedAt    time.Time `json:"finished_at"`
+}
+
+// DomainSummary describes a crawled store
+type DomainSummary struct {
+	Domain       string         `json:"domain"`
+	Provider     string         `json:"provider,omitempty"`
+	ProductCount int            `json:"product_count"`
+	StatusCounts map[string]int `json:"status_counts"`
+	// LastCrawl is nil for products saved before crawls were recorded
+	LastCrawl *CrawlRecord `json:"last_crawl"`
+	// NextCrawlAt is when the domain is next due to be crawled: the end of its
+	// last crawl plus the configured crawl interval. It is nil without a last
+	// crawl or when no interval is configured.
+	NextCrawlAt *time.Time `json:"next_crawl_at"`
+}
+
+// DomainSortKey names the order domains are listed in
+type DomainSortKey string
+
+const (
+	SortDomainName    DomainSortKey = "domain"
+	SortProductCount  DomainSortKey = "product_count"
+	SortLastCrawledAt DomainSortKey = "last_crawled_at"
+)
+
+// ParseDomainSortKey validates a sort key given by a client; an empty value is
+// SortDomainName
+func ParseDomainSortKey(value string) (DomainSortKey, error) {
+	switch key := DomainSortKey(value); key {
+	case "":
+		return SortDomainName, nil
+	case SortDomainName, SortProductCount, SortLastCrawledAt:
+		return key, nil
+	default:
+		return "", fmt.Errorf("unknown sort key %q", value)
+	}
+}
+
+// DomainQuery orders and pages the domain catalogue. Domains never crawled sort
+// last by SortLastCrawledAt in either direction.
+type DomainQuery struct {
+	Sort       DomainSortKey
+	Descending bool
+	// Page starts at 1
+	Page     int
+	PageSize int
+}
+
+// Offset is the number of domains before the page
+func (q DomainQuery) Offset() int {
+	return max(q.Page-1, 0) * q.PageSize
+}
+
+// DomainPage is one page of the domain catalogue
+type DomainPage struct {
+	Domains    []*DomainSummary
+	TotalItems int
+}
+
+// ErrDomainNotFound is returned for a domain without stored products
+var ErrDomainNotFound = errors.New("domain not found")
+
+// DomainStatsTopTags is how many of the most used tags DomainStats lists
+const DomainStatsTopTags = 10
+
+// PriceBucketCount is the most buckets a price distribution is split into
+const PriceBucketCount = 10
+
+// DomainStats summarises the stored products of a domain
+type DomainStats struct {
+	Domain       string `json:"domain"`
+	ProductCount int    `json:"product_count"`
+	// InStockCount counts the products not out of stock, see domain.Product.InStock
+	InStockCount int     `json:"in_stock_count"`
+	InStockRatio float64 `json:"in_stock_ratio"`
+	// Price summarises list prices
+	Price       PriceStats `json:"price"`
+	OnSaleCount int        `json:"on_sale_count"`
+	// AverageDiscount is the mean discount percentage of the products on sale
+	AverageDiscount float64 `json:"average_discount"`
+	// TopTags are the most used tags, most used first. Shopline tags are
+	// category IDs.
+	TopTags          []TagCount `json:"top_tags"`
+	ImagesPerProduct float64    `json:"images_per_product"`
+}
+
+// PriceStats describes the spread of list prices
+type PriceStats struct {
+	Min int `json:"min"`
+	// Median is the mean of the two middle prices for an even number of products
+	Median  float64       `json:"median"`
+	Max     int           `json:"max"`
+	Buckets []PriceBucket `json:"buckets"`
+}
+
+// PriceBucket counts the products priced from Min up to but excluding Max
+type PriceBucket struct {
+	Min   int `json:"min"`
+	Max   int `json:"max"`
+	Count int `json:"count"`
+}
+
+// TagCount is how many products carry a tag
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// NewPriceBuckets splits the prices from low to high into at most
+// PriceBucketCount empty buckets of the same whole width
+func NewPriceBuckets(low, high int) []PriceBucket {
+	width := max((high-low)/PriceBucketCount+1, 1)
+	var buckets []PriceBucket
+	for start := low; start <= high; start += width {
+		buckets = append(buckets, PriceBucket{Min: start, Max: start + width})
+	}
+	return buckets
+}
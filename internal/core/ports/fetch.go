@@ -0,0 +1,126 @@
+package ports
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// FetchMetadata describes how a fetched body was obtained
+type FetchMetadata struct {
+	URL string `json:"url"`
+	// ContentType is the Content-Type response header, restored from the cache entry
+	// metadata for cache hits
+	ContentType string `json:"content_type,omitempty"`
+	// Charset is the encoding the page was served in, before it was transcoded to UTF-8
+	Charset   string `json:"charset"`
+	FromCache bool   `json:"from_cache"`
+}
+
+// FetchMetadataProvider is implemented by bodies returned from HTMLFetcher.Fetch
+// that carry metadata about the fetch.
+type FetchMetadataProvider interface {
+	FetchMetadata() FetchMetadata
+}
+
+// BufferedBody is implemented by readers that already hold their whole content in
+// memory, so consumers can use the buffer instead of copying it. The returned slice
+// is the unread remainder and must not be modified.
+type BufferedBody interface {
+	Bytes() []byte
+}
+
+// ReadAll reads r to the end like io.ReadAll, but reuses the buffer of a BufferedBody
+func ReadAll(r io.Reader) ([]byte, error) {
+	buffered, ok := r.(BufferedBody)
+	if !ok {
+		return io.ReadAll(r)
+	}
+	data := buffered.Bytes()
+	// Leave the reader drained, as io.ReadAll would
+	if seeker, ok := r.(io.Seeker); ok {
+		if _, err := seeker.Seek(0, io.SeekEnd); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// CacheMode controls how a fetch uses the cache
+type CacheMode string
+
+const (
+	// CacheModeUse serves cached responses and caches new downloads
+	CacheModeUse CacheMode = "use"
+	// CacheModeRefresh always downloads and replaces the cached response
+	CacheModeRefresh CacheMode = "refresh"
+	// CacheModeBypass always downloads and neither reads nor writes the cache
+	CacheModeBypass CacheMode = "bypass"
+)
+
+// ParseCacheMode validates a cache mode from a request; empty means CacheModeUse
+func ParseCacheMode(value string) (CacheMode, error) {
+	switch mode := CacheMode(value); mode {
+	case "":
+		return CacheModeUse, nil
+	case CacheModeUse, CacheModeRefresh, CacheModeBypass:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown cache mode %q, expected use, refresh or bypass", value)
+	}
+}
+
+// ReadsCache reports whether cached responses may be served
+func (m CacheMode) ReadsCache() bool {
+	return m == "" || m == CacheModeUse
+}
+
+// WritesCache reports whether downloaded responses may be cached
+func (m CacheMode) WritesCache() bool {
+	return m != CacheModeBypass
+}
+
+// FetchOptions carries per-crawl settings from the core services to the fetcher
+type FetchOptions struct {
+	// Provider is the name of the provider the crawl is using, e.g. "shopline.tw"
+	Provider string
+	// CacheMode is how the fetches of the crawl use the cache; empty means CacheModeUse
+	CacheMode CacheMode
+	// RunID identifies the crawl run the fetch belongs to
+	RunID string
+}
+
+type fetchOptionsKey struct{}
+
+// WithFetchOptions attaches fetch options to the context passed down to HTMLFetcher.Fetch
+func WithFetchOptions(ctx context.Context, options FetchOptions) context.Context {
+	return context.WithValue(ctx, fetchOptionsKey{}, options)
+}
+
+// FetchOptionsFromContext returns the options attached with WithFetchOptions, or the zero value
+func FetchOptionsFromContext(ctx context.Context) FetchOptions {
+	options, _ := ctx.Value(fetchOptionsKey{}).(FetchOptions)
+	return options
+}
+
+// RecordedExchange is one HTTP request and the response received for it
+type RecordedExchange struct {
+	RunID          string
+	URL            string
+	Method         string
+	RequestHeader  http.Header
+	Proto          string
+	StatusCode     int
+	Status         string
+	ResponseHeader http.Header
+	// Body is the response payload after any transfer and content decoding
+	Body      []byte
+	FetchedAt time.Time
+}
+
+// FetchRecorder archives the exchanges made by the fetcher
+type FetchRecorder interface {
+	Record(ctx context.Context, exchange RecordedExchange) error
+}
@@ -2,6 +2,8 @@ package ports
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"web-crawler-go/internal/core/domain"
 )
@@ -11,9 +13,23 @@ import (
 // ProductService is the interface for the application's business logic.
 // It's called by primary adapters (e.g., HTTP handlers).
 type ProductService interface {
-	CrawlAndSaveProductsFromURL(ctx context.Context, domainUrl string) (int, error)
+	CrawlAndSaveProductsFromURL(ctx context.Context, domainUrl string, options CrawlOptions) (int, error)
 	GetProviderFromURL(ctx context.Context, domainUrl string) (ProductProvider, error)
-	GetProductsByDomainName(ctx context.Context, domainName string, page, pageSize int) ([]*domain.Product, int, error)
+	// ListProducts returns a page of the products matching the query along with
+	// the number of products matching it in total
+	ListProducts(ctx context.Context, query ProductQuery) (*ProductPage, error)
+	// GetProduct returns the product with the ID, or ErrProductNotFound
+	GetProduct(ctx context.Context, id string) (*domain.Product, error)
+	// ListDomains returns a page of the crawled domains
+	ListDomains(ctx context.Context, query DomainQuery) (*DomainPage, error)
+	// GetDomainStats summarises the products of a domain, or returns ErrDomainNotFound
+	GetDomainStats(ctx context.Context, domainName string) (*DomainStats, error)
+}
+
+// CrawlOptions tunes a single crawl
+type CrawlOptions struct {
+	// CacheMode is passed down to every fetch of the crawl
+	CacheMode CacheMode
 }
 
 // --- Secondary/Driven Ports ---
@@ -32,9 +48,58 @@ type ProductProvider interface {
 // ProductRepository is an interface for persisting products.
 type ProductRepository interface {
 	UpsertProduct(ctx context.Context, product *domain.Product) error
+	// UpsertProducts saves a batch and returns how many products were saved.
+	// Products that fail individually are reported in a *ProductUpsertError
+	// while the rest of the batch is still saved.
+	UpsertProducts(ctx context.Context, products []*domain.Product) (int, error)
 	Close(ctx context.Context) error
-	GetProducts(ctx context.Context, domainName string, page, pageSize int) ([]*domain.Product, error)
-	GetTotalProducts(ctx context.Context, domainName string) (int, error)
+	// GetProducts returns a page of the products matching the query and the
+	// cursor of the next page
+	GetProducts(ctx context.Context, query ProductQuery) (*ProductPage, error)
+	// GetTotalProducts counts the products matching the query, ignoring its page
+	GetTotalProducts(ctx context.Context, query ProductQuery) (int, error)
+	// GetProduct returns the product with the ID, or ErrProductNotFound
+	GetProduct(ctx context.Context, id string) (*domain.Product, error)
+	// RecordCrawl stores the outcome of a crawl
+	RecordCrawl(ctx context.Context, crawl CrawlRecord) error
+	// ListDomains returns a page of the domains that have stored products or
+	// recorded crawls, with their product counts and latest crawl
+	ListDomains(ctx context.Context, query DomainQuery) (*DomainPage, error)
+	// GetDomainStats summarises the products of a domain; ProductCount is 0 and
+	// the rest zero-valued when it has none
+	GetDomainStats(ctx context.Context, domainName string) (*DomainStats, error)
+}
+
+// ErrProductNotFound is returned when no product has the requested ID
+var ErrProductNotFound = errors.New("product not found")
+
+// ProductUpsertFailure is one product of a batch that could not be saved
+type ProductUpsertFailure struct {
+	// Index is the product's position in the batch
+	Index int
+	Name  string
+	Err   error
+}
+
+// ProductUpsertError lists the products of a batch that could not be saved
+type ProductUpsertError struct {
+	Failures []ProductUpsertFailure
+}
+
+func (e *ProductUpsertError) Error() string {
+	if len(e.Failures) == 1 {
+		return fmt.Sprintf("failed to upsert product %q: %v", e.Failures[0].Name, e.Failures[0].Err)
+	}
+	return fmt.Sprintf("failed to upsert %d products, first %q: %v", len(e.Failures), e.Failures[0].Name, e.Failures[0].Err)
+}
+
+// Unwrap returns the individual errors
+func (e *ProductUpsertError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, failure := range e.Failures {
+		errs[i] = failure.Err
+	}
+	return errs
 }
 
 // SSEService is an interface for Server-Sent Events functionality.
@@ -0,0 +1,95 @@
+package ports
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"web-crawler-go/internal/core/domain"
+)
+
+// ErrInvalidCursor is returned for a cursor that is malformed or was issued for a
+// different sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ProductSortKey names the order products are listed in
+type ProductSortKey string
+
+const (
+	// SortInserted lists products in the order they were first saved
+	SortInserted  ProductSortKey = ""
+	SortPrice     ProductSortKey = "price"
+	SortName      ProductSortKey = "name"
+	SortUpdatedAt ProductSortKey = "updated_at"
+	// SortDiscount orders by discount percentage, see domain.Product.DiscountPercent
+	SortDiscount ProductSortKey = "discount"
+)
+
+// ParseProductSortKey validates a sort key given by a client; an empty value is
+// SortInserted
+func ParseProductSortKey(value string) (ProductSortKey, error) {
+	switch key := ProductSortKey(value); key {
+	case SortInserted, SortPrice, SortName, SortUpdatedAt, SortDiscount:
+		return key, nil
+	default:
+		return "", fmt.Errorf("unknown sort key %q", value)
+	}
+}
+
+// ProductQuery selects, orders and pages the products of a domain. Zero-valued
+// filters match every product.
+type ProductQuery struct {
+	Domain string
+	// Status matches the product status exactly
+	Status string
+	// Tag matches products carrying the tag
+	Tag string
+	// MinPrice and MaxPrice bound the list price, inclusive
+	MinPrice *int
+	MaxPrice *int
+	// OnSale keeps only products with a discounted price below the list price
+	OnSale bool
+	// UpdatedSince keeps products saved at or after the time
+	UpdatedSince time.Time
+	// CreatedSince keeps products first saved at or after the time
+	CreatedSince time.Time
+	// CrawledSince and CrawledBefore bound when products were last saved by a
+	// crawl, inclusive and exclusive; either leaves out products never crawled
+	CrawledSince  time.Time
+	CrawledBefore time.Time
+	// CrawlRunID keeps the products last saved by the crawl run
+	CrawlRunID string
+	// Provider matches the provider that parsed the product
+	Provider string
+	// Search matches words in the name or description
+	Search string
+
+	Sort       ProductSortKey
+	Descending bool
+
+	// Page starts at 1
+	Page     int
+	PageSize int
+	// Cursor continues a listing after the page that returned it as NextCursor.
+	// When set, Page is ignored.
+	Cursor string
+}
+
+// Offset is the number of products before the page; cursor pages start right
+// after the cursor
+func (q ProductQuery) Offset() int {
+	if q.Cursor != "" {
+		return 0
+	}
+	return max(q.Page-1, 0) * q.PageSize
+}
+
+// ProductPage is one page of a product listing
+type ProductPage struct {
+	Products []*domain.Product
+	// NextCursor continues the listing after this page; empty on the last page
+	NextCursor string
+	// TotalItems counts every product matching the query. It is filled in by
+	// ProductService.ListProducts; repositories count with GetTotalProducts.
+	TotalItems int
+}
@@ -0,0 +1,21 @@
+package ports
+
+import "time"
+
+// ProxyStatus describes the health of a single outbound proxy
+type ProxyStatus struct {
+	URL                 string    `json:"url"`
+	Healthy             bool      `json:"healthy"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	TotalRequests       int64     `json:"total_requests"`
+	TotalFailures       int64     `json:"total_failures"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastFailureAt       time.Time `json:"last_failure_at,omitzero"`
+	CooldownUntil       time.Time `json:"cooldown_until,omitzero"`
+}
+
+// ProxyMonitor exposes the health of the fetcher's outbound proxy pool
+type ProxyMonitor interface {
+	// ProxyStatuses returns one entry per configured proxy, empty when no pool is configured
+	ProxyStatuses() []ProxyStatus
+}
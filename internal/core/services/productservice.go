@@ -2,9 +2,12 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"golang.org/x/net/html"
+	"net/url"
 	"time"
 	"web-crawler-go/internal/core/domain"
 	"web-crawler-go/internal/core/ports"
@@ -15,6 +18,10 @@ const (
 	ShopLineURL    = "https://cdn.shoplineapp.com"
 )
 
+// saveBatchSize is how many products are handed to the repository at once; a
+// save_progress event is sent after each batch
+const saveBatchSize = 100
+
 var ErrProviderNotFound = errors.New("suitable provider not found for the given URL")
 
 // productService implements the ProductService port.
@@ -23,43 +30,67 @@ type productService struct {
 	providerRegistry map[string]ports.ProductProvider // Maps hostname -> provider
 	repository       ports.ProductRepository
 	sseService       ports.SSEService
-	logger           ports.Logger
+	// crawlInterval is how long after a crawl a domain is due again; zero
+	// leaves DomainSummary.NextCrawlAt unset
+	crawlInterval time.Duration
+	logger        ports.Logger
 }
 
-// NewProductService creates a new instance of the product service.
-func NewProductService(fetcher ports.HTMLFetcher, registry map[string]ports.ProductProvider, repository ports.ProductRepository, sseService ports.SSEService, logger ports.Logger) ports.ProductService {
+// NewProductService creates a new instance of the product service. Domains are
+// due to be crawled again crawlInterval after their last crawl; zero disables
+// the schedule.
+func NewProductService(fetcher ports.HTMLFetcher, registry map[string]ports.ProductProvider, repository ports.ProductRepository, sseService ports.SSEService, crawlInterval time.Duration, logger ports.Logger) ports.ProductService {
 	return &productService{
 		fetcher:          fetcher,
 		providerRegistry: registry,
 		repository:       repository,
 		sseService:       sseService,
+		crawlInterval:    crawlInterval,
 		logger:           logger,
 	}
 }
 
 func (p *productService) GetProviderFromURL(ctx context.Context, domainUrl string) (ports.ProductProvider, error) {
+	_, provider, err := p.identifyProvider(ctx, domainUrl)
+	return provider, err
+}
+
+// identifyProvider returns the registry name of the provider along with the provider itself
+func (p *productService) identifyProvider(ctx context.Context, domainUrl string) (string, ports.ProductProvider, error) {
 	p.logger.Info("fetching HTML", "domainUrl", domainUrl)
 	// 1. Fetch the HTML
 	htmlBody, err := p.fetcher.Fetch(ctx, domainUrl)
 	if err != nil {
 		p.logger.Error("failed to fetch HTML", "error", err)
-		return nil, err
+		return "", nil, err
 	}
 	defer htmlBody.Close()
 
 	// 2. Parse the HTML document
 	doc, err := html.Parse(htmlBody)
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 
 	if hasDNSPrefetchLink(doc, ShopLineURL) {
-		provider := "shopline.tw"
-		p.logger.Info("provider identified", "provider", provider)
-		return p.providerRegistry[provider], nil
+		name := "shopline.tw"
+		provider, ok := p.providerRegistry[name]
+		if !ok {
+			p.logger.Error("identified provider is not registered", "provider", name)
+			return "", nil, ErrProviderNotFound
+		}
+		p.logger.Info("provider identified", "provider", name)
+		return name, provider, nil
 	}
 
-	return nil, ErrProviderNotFound
+	return "", nil, ErrProviderNotFound
+}
+
+// newCrawlRunID returns a sortable, unique identifier for a crawl run
+func newCrawlRunID() string {
+	var suffix [4]byte
+	_, _ = rand.Read(suffix[:])
+	return fmt.Sprintf("crawl-%s-%s", time.Now().UTC().Format("20060102T150405Z"), hex.EncodeToString(suffix[:]))
 }
 
 func hasDNSPrefetchLink(n *html.Node, targetURL string) bool {
@@ -85,8 +116,34 @@ func hasDNSPrefetchLink(n *html.Node, targetURL string) bool {
 	return false
 }
 
-func (p *productService) CrawlAndSaveProductsFromURL(ctx context.Context, domainUrl string) (int, error) {
-	p.logger.Info("getting products from domainUrl", "domainUrl", domainUrl)
+func (p *productService) CrawlAndSaveProductsFromURL(ctx context.Context, domainUrl string, options ports.CrawlOptions) (productsCount int, err error) {
+	p.logger.Info("getting products from domainUrl", "domainUrl", domainUrl, "cacheMode", options.CacheMode)
+
+	// Every fetch of the crawl, including the provider's, follows the requested cache
+	// mode and is tagged with the run so recordings can be traced back to it
+	runID := newCrawlRunID()
+	fetchOptions := ports.FetchOptions{CacheMode: options.CacheMode, RunID: runID}
+	ctx = ports.WithFetchOptions(ctx, fetchOptions)
+
+	// Products are stored under the host they were crawled from
+	domainName := domainUrl
+	if parsed, err := url.Parse(domainUrl); err == nil && parsed.Hostname() != "" {
+		domainName = parsed.Hostname()
+	}
+
+	// Record how the crawl ended, whichever way it returns
+	crawl := ports.CrawlRecord{RunID: runID, Domain: domainName, StartedAt: time.Now()}
+	defer func() {
+		crawl.Provider = fetchOptions.Provider
+		crawl.ProductsCount = productsCount
+		crawl.Status = ports.CrawlCompleted
+		if err != nil {
+			crawl.Status = ports.CrawlFailed
+			crawl.Error = err.Error()
+		}
+		crawl.FinishedAt = time.Now()
+		p.recordCrawl(ctx, crawl)
+	}()
 
 	// Send crawling started notification
 	p.sseService.Broadcast(ctx, ports.SSEMessage{
@@ -94,14 +151,18 @@ func (p *productService) CrawlAndSaveProductsFromURL(ctx context.Context, domain
 		Event: "crawl_started",
 		Data: map[string]interface{}{
 			"domain_url": domainUrl,
+			"run_id":     runID,
 			"status":     "started",
 			"message":    "Starting to crawl domain",
 		},
 	})
 
 	// 1. Identify the provider from the URL
-	provider, err := p.GetProviderFromURL(ctx, domainUrl)
-	if err != nil || provider == nil {
+	providerName, provider, err := p.identifyProvider(ctx, domainUrl)
+	if err == nil && provider == nil {
+		err = ErrProviderNotFound
+	}
+	if err != nil {
 		p.logger.Error("failed to get provider from domainUrl", "error", err)
 		// Send error notification
 		p.sseService.Broadcast(ctx, ports.SSEMessage{
@@ -129,7 +190,10 @@ func (p *productService) CrawlAndSaveProductsFromURL(ctx context.Context, domain
 		},
 	})
 
-	// 2. Fetch the HTML content using the fetcher port
+	// 2. Fetch the HTML content using the fetcher port; the provider name lets
+	// the fetcher pick provider-specific cache TTLs
+	fetchOptions.Provider = providerName
+	ctx = ports.WithFetchOptions(ctx, fetchOptions)
 	products, err := provider.ProcessProducts(ctx, domainUrl)
 	if err != nil {
 		p.logger.Error("failed to process products", "error", err)
@@ -160,34 +224,61 @@ func (p *productService) CrawlAndSaveProductsFromURL(ctx context.Context, domain
 		},
 	})
 
-	// 3. Save each product to DB
-	savedCount := 0
-	for i, product := range products {
-		if err := p.repository.UpsertProduct(ctx, product); err != nil {
-			p.logger.Error("failed to save product to DB", "error", err, "product", product.Name)
-			// Continue processing other products even if one fails
-			continue
+	// 3. Save the products to DB in batches
+	for _, product := range products {
+		if product.Domain == "" {
+			product.Domain = domainName
 		}
-		savedCount++
-
-		// Send progress update every 10 products or on the last product
-		if (i+1)%10 == 0 || i == len(products)-1 {
-			p.sseService.Broadcast(ctx, ports.SSEMessage{
-				ID:    fmt.Sprintf("save-progress-%d", time.Now().Unix()),
-				Event: "save_progress",
-				Data: map[string]interface{}{
-					"domain_url":       domainUrl,
-					"status":           "saving",
-					"message":          "Saving products to database",
-					"saved_count":      savedCount,
-					"total_count":      len(products),
-					"progress_percent": float64(savedCount) / float64(len(products)) * 100,
-				},
-			})
+		product.Provider = providerName
+		product.CrawlRunID = runID
+	}
+
+	savedCount := 0
+	for start := 0; start < len(products); start += saveBatchSize {
+		batch := products[start:min(start+saveBatchSize, len(products))]
+
+		saved, err := p.repository.UpsertProducts(ctx, batch)
+		savedCount += saved
+		if err != nil {
+			var upsertErr *ports.ProductUpsertError
+			if !errors.As(err, &upsertErr) {
+				p.logger.Error("failed to save products to DB", "error", err, "saved", savedCount)
+				// Send error notification
+				p.sseService.Broadcast(ctx, ports.SSEMessage{
+					ID:    fmt.Sprintf("crawl-error-%d", time.Now().Unix()),
+					Event: "crawl_error",
+					Data: map[string]interface{}{
+						"domain_url":  domainUrl,
+						"status":      "error",
+						"message":     "Failed to save products to database",
+						"saved_count": savedCount,
+						"error":       err.Error(),
+					},
+				})
+				return savedCount, err
+			}
+			// Continue processing other products even if some fail
+			for _, failure := range upsertErr.Failures {
+				p.logger.Error("failed to save product to DB", "error", failure.Err, "product", failure.Name)
+			}
 		}
+
+		// Send a progress update after every batch
+		p.sseService.Broadcast(ctx, ports.SSEMessage{
+			ID:    fmt.Sprintf("save-progress-%d", time.Now().Unix()),
+			Event: "save_progress",
+			Data: map[string]interface{}{
+				"domain_url":       domainUrl,
+				"status":           "saving",
+				"message":          "Saving products to database",
+				"saved_count":      savedCount,
+				"total_count":      len(products),
+				"progress_percent": float64(savedCount) / float64(len(products)) * 100,
+			},
+		})
 	}
 
-	productsCount := savedCount
+	productsCount = savedCount
 
 	// Send crawling completed notification
 	p.sseService.Broadcast(ctx, ports.SSEMessage{
@@ -204,16 +295,69 @@ func (p *productService) CrawlAndSaveProductsFromURL(ctx context.Context, domain
 	return productsCount, nil
 }
 
-// GetProductsByDomainName return saved products with pagination
-func (p *productService) GetProductsByDomainName(ctx context.Context, domainName string, page, pageSize int) ([]*domain.Product, int, error) {
-	products, err := p.repository.GetProducts(ctx, domainName, page, pageSize)
+// recordCrawl stores the outcome of a crawl. It still runs when the crawl's
+// request was cancelled, and a failure is only logged.
+func (p *productService) recordCrawl(ctx context.Context, crawl ports.CrawlRecord) {
+	if err := p.repository.RecordCrawl(context.WithoutCancel(ctx), crawl); err != nil {
+		p.logger.Error("failed to record crawl", "runID", crawl.RunID, "domain", crawl.Domain, "error", err)
+	}
+}
+
+// ListDomains returns the catalogue of crawled domains with when each is next
+// due to be crawled
+func (p *productService) ListDomains(ctx context.Context, query ports.DomainQuery) (*ports.DomainPage, error) {
+	page, err := p.repository.ListDomains(ctx, query)
+	if err != nil {
+		p.logger.Error("failed to list domains from DB", "error", err)
+		return nil, err
+	}
+	if p.crawlInterval > 0 {
+		for _, summary := range page.Domains {
+			if summary.LastCrawl != nil {
+				next := summary.LastCrawl.FinishedAt.Add(p.crawlInterval)
+				summary.NextCrawlAt = &next
+			}
+		}
+	}
+	return page, nil
+}
+
+// GetDomainStats summarises the stored products of a domain
+func (p *productService) GetDomainStats(ctx context.Context, domainName string) (*ports.DomainStats, error) {
+	stats, err := p.repository.GetDomainStats(ctx, domainName)
+	if err != nil {
+		p.logger.Error("failed to get domain stats from DB", "domain", domainName, "error", err)
+		return nil, err
+	}
+	if stats.ProductCount == 0 {
+		return nil, ports.ErrDomainNotFound
+	}
+	return stats, nil
+}
+
+// ListProducts returns saved products matching the query with pagination
+func (p *productService) ListProducts(ctx context.Context, query ports.ProductQuery) (*ports.ProductPage, error) {
+	page, err := p.repository.GetProducts(ctx, query)
 	if err != nil {
 		p.logger.Error("failed to get products from DB", "error", err)
-		// Continue processing other products even if one fails
-		return nil, 0, err
+		return nil, err
 	}
 
-	total, err := p.repository.GetTotalProducts(ctx, domainName)
+	total, err := p.repository.GetTotalProducts(ctx, query)
+	if err != nil {
+		p.logger.Error("failed to count products in DB", "error", err)
+		return nil, err
+	}
+	page.TotalItems = total
 
-	return products, total, nil
+	return page, nil
+}
+
+// GetProduct returns a saved product by its ID
+func (p *productService) GetProduct(ctx context.Context, id string) (*domain.Product, error) {
+	product, err := p.repository.GetProduct(ctx, id)
+	if err != nil && !errors.Is(err, ports.ErrProductNotFound) {
+		p.logger.Error("failed to get product from DB", "id", id, "error", err)
+	}
+	return product, err
 }
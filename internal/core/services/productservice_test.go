@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"web-crawler-go/internal/adapters/secondary/repository"
+	"web-crawler-go/internal/core/domain"
+	"web-crawler-go/internal/core/ports"
+	"web-crawler-go/internal/core/services/loggerservice"
+)
+
+const (
+	shoplinePage = `<html><head><link rel="dns-prefetch" href="https://cdn.shoplineapp.com"></head><body></body></html>`
+	unknownPage  = `<html><head><title>Somewhere else</title></head><body></body></html>`
+	storeURL     = "https://shop.example.tw"
+	storeDomain  = "shop.example.tw"
+)
+
+// stubFetcher serves fixed pages by URL
+type stubFetcher struct {
+	pages map[string]string
+}
+
+func (f *stubFetcher) Fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	page, ok := f.pages[url]
+	if !ok {
+		return nil, errors.New("no page for " + url)
+	}
+	return io.NopCloser(strings.NewReader(page)), nil
+}
+
+// stubProvider returns fixed products, or err, and remembers the fetch options
+// the crawl passed down
+type stubProvider struct {
+	products []*domain.Product
+	err      error
+	options  ports.FetchOptions
+}
+
+func (p *stubProvider) Parse(ctx context.Context, html io.Reader) (*domain.Product, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *stubProvider) ProcessProducts(ctx context.Context, domainUrl string) ([]*domain.Product, error) {
+	p.options = ports.FetchOptionsFromContext(ctx)
+	return p.products, p.err
+}
+
+// stubSSE records the events broadcast during a crawl
+type stubSSE struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (s *stubSSE) Subscribe(ctx context.Context, clientID string, messageChan chan<- ports.SSEMessage) error {
+	return nil
+}
+
+func (s *stubSSE) Unsubscribe(clientID string) {}
+
+func (s *stubSSE) Broadcast(ctx context.Context, message ports.SSEMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, message.Event)
+	return nil
+}
+
+func (s *stubSSE) BroadcastToClient(ctx context.Context, clientID string, message ports.SSEMessage) error {
+	return nil
+}
+
+func (s *stubSSE) GetConnectedClients() int {
+	return 0
+}
+
+func (s *stubSSE) last() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.events) == 0 {
+		return ""
+	}
+	return s.events[len(s.events)-1]
+}
+
+// failingRepository saves batches in memory except for the products named in
+// fail, which it reports in a *ports.ProductUpsertError
+type failingRepository struct {
+	*repository.MemoryRepository
+	fail map[string]bool
+}
+
+func (r *failingRepository) UpsertProducts(ctx context.Context, products []*domain.Product) (int, error) {
+	var kept []*domain.Product
+	var failures []ports.ProductUpsertFailure
+	for i, product := range products {
+		if r.fail[product.Name] {
+			failures = append(failures, ports.ProductUpsertFailure{Index: i, Name: product.Name, Err: errors.New("rejected")})
+			continue
+		}
+		kept = append(kept, product)
+	}
+	saved, err := r.MemoryRepository.UpsertProducts(ctx, kept)
+	if err != nil {
+		return saved, err
+	}
+	if len(failures) > 0 {
+		return saved, &ports.ProductUpsertError{Failures: failures}
+	}
+	return saved, nil
+}
+
+func newTestProducts() []*domain.Product {
+	return []*domain.Product{
+		{Name: "Green tea", Price: 300, PriceDiscounted: 250, Status: domain.StatusActive},
+		{Name: "Oolong tea", Price: 450, Status: domain.StatusActive},
+		{Name: "Tea cup", Price: 800, Status: domain.StatusOutOfStock},
+	}
+}
+
+func TestCrawlAndSaveProductsFromURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     string
+		register bool
+		provider *stubProvider
+		fail     map[string]bool
+
+		wantCount  int
+		wantErr    error
+		wantEvent  string
+		wantStatus string
+		wantSaved  []string
+	}{
+		{
+			name:       "success",
+			page:       shoplinePage,
+			register:   true,
+			provider:   &stubProvider{products: newTestProducts()},
+			wantCount:  3,
+			wantEvent:  "crawl_completed",
+			wantStatus: ports.CrawlCompleted,
+			wantSaved:  []string{"Green tea", "Oolong tea", "Tea cup"},
+		},
+		{
+			name:       "provider not found",
+			page:       unknownPage,
+			register:   true,
+			provider:   &stubProvider{products: newTestProducts()},
+			wantErr:    ErrProviderNotFound,
+			wantEvent:  "crawl_error",
+			wantStatus: ports.CrawlFailed,
+		},
+		{
+			name:       "identified provider not registered",
+			page:       shoplinePage,
+			register:   false,
+			provider:   &stubProvider{products: newTestProducts()},
+			wantErr:    ErrProviderNotFound,
+			wantEvent:  "crawl_error",
+			wantStatus: ports.CrawlFailed,
+		},
+		{
+			name:       "provider error",
+			page:       shoplinePage,
+			register:   true,
+			provider:   &stubProvider{err: errors.New("sitemap unavailable")},
+			wantEvent:  "crawl_error",
+			wantStatus: ports.CrawlFailed,
+		},
+		{
+			name:       "partial upsert error",
+			page:       shoplinePage,
+			register:   true,
+			provider:   &stubProvider{products: newTestProducts()},
+			fail:       map[string]bool{"Oolong tea": true},
+			wantCount:  2,
+			wantEvent:  "crawl_completed",
+			wantStatus: ports.CrawlCompleted,
+			wantSaved:  []string{"Green tea", "Tea cup"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			logger := loggerservice.NewLoggerService()
+			repo := &failingRepository{MemoryRepository: repository.NewMemoryRepository(logger), fail: tt.fail}
+			sse := &stubSSE{}
+			registry := map[string]ports.ProductProvider{}
+			if tt.register {
+				registry["shopline.tw"] = tt.provider
+			}
+			fetcher := &stubFetcher{pages: map[string]string{storeURL: tt.page}}
+			service := NewProductService(fetcher, registry, repo, sse, 0, logger)
+
+			count, crawlErr := service.CrawlAndSaveProductsFromURL(ctx, storeURL, ports.CrawlOptions{CacheMode: ports.CacheModeRefresh})
+
+			wantErr := tt.wantErr
+			if wantErr == nil {
+				wantErr = tt.provider.err
+			}
+			if !errors.Is(crawlErr, wantErr) {
+				t.Fatalf("error = %v, want %v", crawlErr, wantErr)
+			}
+			if count != tt.wantCount {
+				t.Errorf("count = %d, want %d", count, tt.wantCount)
+			}
+			if got := sse.last(); got != tt.wantEvent {
+				t.Errorf("last event = %q, want %q (events %v)", got, tt.wantEvent, sse.events)
+			}
+
+			domains, err := repo.ListDomains(ctx, ports.DomainQuery{Page: 1, PageSize: 10})
+			if err != nil {
+				t.Fatalf("ListDomains: %v", err)
+			}
+			if len(domains.Domains) != 1 || domains.Domains[0].LastCrawl == nil {
+				t.Fatalf("domains = %+v, want one with a recorded crawl", domains.Domains)
+			}
+			crawl := domains.Domains[0].LastCrawl
+			if crawl.Domain != storeDomain || crawl.Status != tt.wantStatus || crawl.ProductsCount != tt.wantCount {
+				t.Errorf("crawl = %s %s with %d products, want %s %s with %d",
+					crawl.Domain, crawl.Status, crawl.ProductsCount, storeDomain, tt.wantStatus, tt.wantCount)
+			}
+			if (crawlErr != nil) != (crawl.Error != "") {
+				t.Errorf("recorded error = %q, want it set only when the crawl failed (%v)", crawl.Error, crawlErr)
+			}
+
+			page, err := repo.GetProducts(ctx, ports.ProductQuery{Domain: storeDomain, Sort: ports.SortName, Page: 1, PageSize: 10})
+			if err != nil {
+				t.Fatalf("GetProducts: %v", err)
+			}
+			if len(page.Products) != len(tt.wantSaved) {
+				t.Fatalf("saved %d products, want %v", len(page.Products), tt.wantSaved)
+			}
+			for i, product := range page.Products {
+				if product.Name != tt.wantSaved[i] {
+					t.Errorf("product %d = %q, want %q", i, product.Name, tt.wantSaved[i])
+				}
+				if product.Provider != "shopline.tw" || product.CrawlRunID != crawl.RunID {
+					t.Errorf("product %q provider %q run %q, want shopline.tw and %q", product.Name, product.Provider, product.CrawlRunID, crawl.RunID)
+				}
+			}
+
+			if tt.wantCount > 0 {
+				options := tt.provider.options
+				if options.CacheMode != ports.CacheModeRefresh || options.Provider != "shopline.tw" || options.RunID != crawl.RunID {
+					t.Errorf("provider fetch options = %+v, want refresh, shopline.tw and run %q", options, crawl.RunID)
+				}
+			}
+		})
+	}
+}
+
+func TestListDomainsDerivesNextCrawl(t *testing.T) {
+	ctx := context.Background()
+	logger := loggerservice.NewLoggerService()
+	repo := repository.NewMemoryRepository(logger)
+
+	finishedAt := time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)
+	products := newTestProducts()
+	products[0].Domain = storeDomain
+	// Saved before crawls were recorded, so it has no last crawl
+	products[1].Domain = "legacy.example.tw"
+	if _, err := repo.UpsertProducts(ctx, products[:2]); err != nil {
+		t.Fatalf("UpsertProducts: %v", err)
+	}
+	crawl := ports.CrawlRecord{RunID: "run-1", Domain: storeDomain, Status: ports.CrawlCompleted, StartedAt: finishedAt.Add(-time.Minute), FinishedAt: finishedAt}
+	if err := repo.RecordCrawl(ctx, crawl); err != nil {
+		t.Fatalf("RecordCrawl: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		crawlInterval time.Duration
+		// wantNext is the next crawl of storeDomain; zero means none
+		wantNext time.Time
+	}{
+		{name: "interval", crawlInterval: 24 * time.Hour, wantNext: finishedAt.Add(24 * time.Hour)},
+		{name: "no interval", crawlInterval: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewProductService(&stubFetcher{}, nil, repo, &stubSSE{}, tt.crawlInterval, logger)
+			page, err := service.ListDomains(ctx, ports.DomainQuery{Sort: ports.SortDomainName, Page: 1, PageSize: 10})
+			if err != nil {
+				t.Fatalf("ListDomains: %v", err)
+			}
+			if len(page.Domains) != 2 {
+				t.Fatalf("listed %d domains, want 2", len(page.Domains))
+			}
+			for _, summary := range page.Domains {
+				var want time.Time
+				if summary.Domain == storeDomain {
+					want = tt.wantNext
+				}
+				switch {
+				case want.IsZero() && summary.NextCrawlAt != nil:
+					t.Errorf("%s next crawl = %v, want none", summary.Domain, *summary.NextCrawlAt)
+				case !want.IsZero() && (summary.NextCrawlAt == nil || !summary.NextCrawlAt.Equal(want)):
+					t.Errorf("%s next crawl = %v, want %v", summary.Domain, summary.NextCrawlAt, want)
+				}
+			}
+		})
+	}
+}
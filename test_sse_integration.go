@@ -19,7 +19,7 @@ type MockProductService struct {
 	logger     ports.Logger
 }
 
-func (m *MockProductService) CrawlAndSaveProductsFromURL(ctx context.Context, domainUrl string) (int, error) {
+func (m *MockProductService) CrawlAndSaveProductsFromURL(ctx context.Context, domainUrl string, options ports.CrawlOptions) (int, error) {
 	m.logger.Info("Mock crawling started", "domainUrl", domainUrl)
 
 	// Send crawling started notification
@@ -102,8 +102,46 @@ func (m *MockProductService) GetProviderFromURL(ctx context.Context, domainUrl s
 	return nil, fmt.Errorf("mock service - not implemented")
 }
 
-func (m *MockProductService) GetProductsByDomainName(ctx context.Context, domainName string, page, pageSize int) ([]*domain.Product, int, error) {
-	return nil, 0, fmt.Errorf("mock service - not implemented")
+func (m *MockProductService) ListProducts(ctx context.Context, query ports.ProductQuery) (*ports.ProductPage, error) {
+	return nil, fmt.Errorf("mock service - not implemented")
+}
+
+func (m *MockProductService) GetProduct(ctx context.Context, id string) (*domain.Product, error) {
+	return nil, ports.ErrProductNotFound
+}
+
+func (m *MockProductService) ListDomains(ctx context.Context, query ports.DomainQuery) (*ports.DomainPage, error) {
+	return nil, fmt.Errorf("mock service - not implemented")
+}
+
+func (m *MockProductService) GetDomainStats(ctx context.Context, domainName string) (*ports.DomainStats, error) {
+	return nil, ports.ErrDomainNotFound
+}
+
+// mockProxyMonitor reports an empty proxy pool
+type mockProxyMonitor struct{}
+
+func (mockProxyMonitor) ProxyStatuses() []ports.ProxyStatus {
+	return []ports.ProxyStatus{}
+}
+
+// mockCacheAdmin stands in for the fetch cache, which the mock service does not use
+type mockCacheAdmin struct{}
+
+func (mockCacheAdmin) LookupURL(ctx context.Context, url string) (*ports.CachedURL, bool, error) {
+	return nil, false, nil
+}
+
+func (mockCacheAdmin) InvalidateURL(ctx context.Context, url string) error {
+	return nil
+}
+
+func (mockCacheAdmin) InvalidateDomain(ctx context.Context, domain string) (int, error) {
+	return 0, nil
+}
+
+func (mockCacheAdmin) CacheStats(ctx context.Context) (ports.CacheStats, error) {
+	return ports.CacheStats{Backend: "mock"}, nil
 }
 
 func main() {
@@ -122,7 +160,7 @@ func main() {
 	}
 
 	// Create router with mock service
-	router := httpadapter.NewRouter(mockProductService, sseService, logger)
+	router := httpadapter.NewRouter(mockProductService, sseService, mockProxyMonitor{}, mockCacheAdmin{}, logger)
 
 	// Setup routes
 	handler := router.SetupRoutes()